@@ -0,0 +1,399 @@
+package web
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errBindFailed = NewHTTPError(http.StatusBadRequest, "bind_failed", "请求参数不合法")
+
+// bindSources 支持的数据来源，字段有多个标签时按照该顺序取第一个有值的来源
+var bindSources = []string{"path", "query", "form", "header", "cookie"}
+
+// BindError 单个字段绑定失败
+type BindError struct {
+	// Field 字段路径，例如 Page、Filter.Status
+	Field string
+	// Source 数据来源，例如 query、header
+	Source string
+	// Name 数据来源中的参数名
+	Name string
+	// Value 绑定失败的值
+	Value string
+	Err   error
+}
+
+func (e *BindError) Error() string {
+	return fmt.Sprintf("web: 字段 %s 绑定失败，%s 参数 %s 的值 %q 不合法: %v", e.Field, e.Source, e.Name, e.Value, e.Err)
+}
+
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+// BindErrors 所有绑定失败的字段
+type BindErrors []*BindError
+
+func (e BindErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Bind 根据结构体字段的标签，从路径参数、查询参数、表单、请求头以及 cookie 中取值并绑定到 dst 上
+//
+//	type Query struct {
+//		ID      int64     `path:"id"`
+//		Page    int       `query:"page"`
+//		Tags    []string  `query:"tag"`
+//		Since   time.Time `query:"since" time_format:"2006-01-02"`
+//		Token   *string   `header:"X-Token"`
+//		Lang    string    `cookie:"lang"`
+//	}
+//
+// 支持字符串、整数、浮点数、布尔值、time.Time、time.Duration、
+// 实现了 encoding.TextUnmarshaler 的类型，以及这些类型的切片和指针
+// 没有值的字段保持不变，绑定失败时返回的错误中包含 BindErrors
+// multipart 表单中的文件可以绑定到 *multipart.FileHeader 和 []*multipart.FileHeader 类型的字段上，
+// max_size 标签限制单个文件的大小，例如 `form:"avatar" max_size:"2MB"`，解析表单时超过限制会立刻停止读取并返回 ErrFileTooLarge
+// 标签不合法时返回错误
+// 请求带有请求体时先根据 Content-Type 选择编解码器反序列化，再绑定带有标签的字段
+// 不支持的 Content-Type 返回 415 对应的 HTTPError
+// 绑定成功后根据 validate 标签进行校验，校验失败时返回的错误中包含 ValidationErrors
+func (c *Context) Bind(dst any) error {
+	val := reflect.ValueOf(dst)
+	if val.Kind() != reflect.Pointer || val.IsNil() || val.Elem().Kind() != reflect.Struct {
+		return errors.New("web: Bind 的参数必须是结构体指针")
+	}
+
+	fields, err := cachedFields(val.Elem().Type())
+	if err != nil {
+		return err
+	}
+
+	if err = c.decodeBody(dst); err != nil {
+		return err
+	}
+
+	if c.isMultipart() {
+		// 解析表单的同时检查文件大小，而不是缓存了整个文件之后再检查
+		limits := map[string]bindField{}
+		for _, f := range fields {
+			if f.file {
+				limits[f.names["form"]] = f
+			}
+		}
+
+		err = c.parseMultipartLimit(limits)
+		var be *BindError
+		if errors.As(err, &be) {
+			return ErrFileTooLarge.WithError(BindErrors{be})
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	var (
+		errs     BindErrors
+		tooLarge bool
+	)
+
+	for _, f := range fields {
+		if f.file {
+			if err := c.bindFile(val.Elem().FieldByIndex(f.index), f); err != nil {
+				errs = append(errs, err)
+				tooLarge = tooLarge || err.Err == ErrFileTooLarge
+			}
+			continue
+		}
+
+		vals, source, name := c.lookupBindValue(f)
+		if len(vals) == 0 {
+			continue
+		}
+
+		field := val.Elem().FieldByIndex(f.index)
+		if err := setField(field, vals, f.timeFormat); err != nil {
+			errs = append(errs, &BindError{
+				Field:  f.path,
+				Source: source,
+				Name:   name,
+				Value:  strings.Join(vals, ","),
+				Err:    err,
+			})
+		}
+	}
+
+	if tooLarge {
+		return ErrFileTooLarge.WithError(errs)
+	}
+	if len(errs) > 0 {
+		return errBindFailed.WithError(errs)
+	}
+
+	return c.validate(dst)
+}
+
+// lookupBindValue 按照来源的顺序获取字段的值
+func (c *Context) lookupBindValue(f bindField) ([]string, string, string) {
+	for _, source := range bindSources {
+		name, ok := f.names[source]
+		if !ok {
+			continue
+		}
+		if vals := c.bindValues(source, name); len(vals) > 0 {
+			return vals, source, name
+		}
+	}
+	return nil, "", ""
+}
+
+func (c *Context) bindValues(source, name string) []string {
+	switch source {
+	case "path":
+		if val, ok := c.PathParams[name]; ok {
+			return []string{val}
+		}
+	case "query":
+		if c.QueryData == nil {
+			c.QueryData = c.Req.URL.Query()
+		}
+		return c.QueryData[name]
+	case "form":
+		if err := c.Req.ParseForm(); err != nil {
+			return nil
+		}
+		return c.Req.Form[name]
+	case "header":
+		return c.Req.Header.Values(name)
+	case "cookie":
+		if ck, err := c.Req.Cookie(name); err == nil {
+			return []string{ck.Value}
+		}
+	}
+	return nil
+}
+
+// bindField 需要绑定的字段
+type bindField struct {
+	index []int
+	path  string
+	// names 数据来源到参数名的映射
+	names      map[string]string
+	timeFormat string
+	// file 字段为 *multipart.FileHeader 或者 []*multipart.FileHeader，只从 multipart 表单中获取
+	file bool
+	// maxSize max_size 标签设置的单个文件的大小限制
+	maxSize int64
+}
+
+// bindFieldCache 缓存每个结构体需要绑定的字段，避免每次请求都解析标签
+var bindFieldCache sync.Map
+
+func cachedFields(typ reflect.Type) ([]bindField, error) {
+	if fields, ok := bindFieldCache.Load(typ); ok {
+		return fields.([]bindField), nil
+	}
+	fields, err := parseFields(typ, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	bindFieldCache.Store(typ, fields)
+	return fields, nil
+}
+
+func parseFields(typ reflect.Type, index []int, prefix string) ([]bindField, error) {
+	var res []bindField
+
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+
+		// 匿名字段可能是未导出的结构体，依旧需要展开
+		if !sf.IsExported() && !sf.Anonymous {
+			continue
+		}
+
+		idx := append(append([]int{}, index...), i)
+
+		path := sf.Name
+		if prefix != "" {
+			path = prefix + "." + sf.Name
+		}
+
+		names := map[string]string{}
+		for _, source := range bindSources {
+			if name, ok := sf.Tag.Lookup(source); ok && name != "-" {
+				names[source] = name
+			}
+		}
+
+		if len(names) > 0 {
+			f := bindField{
+				index:      idx,
+				path:       path,
+				names:      names,
+				timeFormat: sf.Tag.Get("time_format"),
+				file:       isFileType(sf.Type),
+			}
+			if size, ok := sf.Tag.Lookup("max_size"); ok {
+				var err error
+				if f.maxSize, err = parseSize(size); err != nil {
+					return nil, fmt.Errorf("web: 字段 %s 的 max_size 标签不合法: %w", path, err)
+				}
+			}
+			res = append(res, f)
+			continue
+		}
+
+		// 没有标签的嵌套结构体，展开其中的字段
+		if sf.Type.Kind() == reflect.Struct && sf.Type != timeType {
+			if sf.Anonymous {
+				path = prefix
+			}
+			nested, err := parseFields(sf.Type, idx, path)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, nested...)
+		}
+	}
+
+	return res, nil
+}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// setField 将字符串转换为字段的类型并赋值
+func setField(field reflect.Value, vals []string, timeFormat string) error {
+	typ := field.Type()
+
+	// 切片的指针，所有的值都绑定到切片上
+	if typ.Kind() == reflect.Pointer && isSlice(typ.Elem()) {
+		elem := reflect.New(typ.Elem())
+		if err := setField(elem.Elem(), vals, timeFormat); err != nil {
+			return err
+		}
+		field.Set(elem)
+		return nil
+	}
+
+	if isSlice(typ) {
+		slice := reflect.MakeSlice(typ, len(vals), len(vals))
+		for i, val := range vals {
+			if err := setValue(slice.Index(i), val, timeFormat); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+
+	return setValue(field, vals[0], timeFormat)
+}
+
+// isSlice 判断是否为需要绑定多个值的切片，[]byte 按照字符串处理
+func isSlice(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Slice && typ.Elem().Kind() != reflect.Uint8
+}
+
+func setValue(field reflect.Value, val, timeFormat string) error {
+	typ := field.Type()
+
+	if typ.Kind() == reflect.Pointer {
+		elem := reflect.New(typ.Elem())
+		if err := setValue(elem.Elem(), val, timeFormat); err != nil {
+			return err
+		}
+		field.Set(elem)
+		return nil
+	}
+
+	switch typ {
+	case timeType:
+		t, err := parseTime(val, timeFormat)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	case durationType:
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	if reflect.PointerTo(typ).Implements(textUnmarshalerType) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(val))
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		field.SetString(val)
+	case reflect.Slice:
+		if typ.Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("不支持的类型 %s", typ)
+		}
+		field.SetBytes([]byte(val))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(val, 10, typ.Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(val, 10, typ.Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(val, typ.Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("不支持的类型 %s", typ)
+	}
+
+	return nil
+}
+
+// parseTime 按照 time_format 解析时间，默认 RFC3339，unix 表示秒级时间戳
+func parseTime(val, layout string) (time.Time, error) {
+	switch layout {
+	case "":
+		return time.Parse(time.RFC3339, val)
+	case "unix":
+		sec, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(sec, 0), nil
+	default:
+		return time.Parse(layout, val)
+	}
+}
@@ -0,0 +1,123 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type bindPage struct {
+	Page int  `query:"page"`
+	Size *int `query:"size"`
+}
+
+type bindQuery struct {
+	bindPage
+
+	ID      int64         `path:"id"`
+	Tags    []string      `query:"tag"`
+	Scores  []float64     `query:"score"`
+	Since   time.Time     `query:"since" time_format:"2006-01-02"`
+	Timeout time.Duration `query:"timeout"`
+	Active  bool          `form:"active"`
+	Token   *string       `header:"X-Token"`
+	Lang    string        `cookie:"lang" query:"lang"`
+	Filter  struct {
+		Status uint8 `query:"status"`
+	}
+	Ignored string
+}
+
+func TestContext_Bind(t *testing.T) {
+	query := url.Values{
+		"page":    {"2"},
+		"size":    {"20"},
+		"tag":     {"a", "b"},
+		"score":   {"1.5", "2"},
+		"since":   {"2023-01-02"},
+		"timeout": {"1s"},
+		"status":  {"3"},
+		"lang":    {"en"},
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/user/12?"+query.Encode(), strings.NewReader("active=true"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Token", "abc")
+	req.AddCookie(&http.Cookie{Name: "lang", Value: "zh"})
+
+	ctx := NewContext(httptest.NewRecorder(), req)
+	ctx.PathParams = map[string]string{"id": "12"}
+
+	var q bindQuery
+	require.NoError(t, ctx.Bind(&q))
+
+	size, token := 20, "abc"
+	want := bindQuery{
+		bindPage: bindPage{Page: 2, Size: &size},
+		ID:       12,
+		Tags:     []string{"a", "b"},
+		Scores:   []float64{1.5, 2},
+		Since:    time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC),
+		Timeout:  time.Second,
+		Active:   true,
+		Token:    &token,
+		// 查询参数排在 cookie 之前
+		Lang: "en",
+	}
+	want.Filter.Status = 3
+
+	assert.Equal(t, want, q)
+}
+
+func TestContext_BindSlicePointer(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/?tag=a&tag=b&id=1&id=2", nil)
+	ctx := NewContext(httptest.NewRecorder(), req)
+
+	var q struct {
+		Tags *[]string `query:"tag"`
+		IDs  []*[]int  `query:"id"`
+	}
+	err := ctx.Bind(&q)
+
+	require.NotNil(t, q.Tags)
+	assert.Equal(t, []string{"a", "b"}, *q.Tags)
+
+	// 切片中的元素不能是切片
+	var errs BindErrors
+	require.True(t, errors.As(err, &errs))
+	require.Len(t, errs, 1)
+	assert.Equal(t, "IDs", errs[0].Field)
+	assert.ErrorContains(t, errs[0], "不支持的类型")
+}
+
+func TestContext_BindErrors(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/user/x?page=a&status=256", nil)
+
+	ctx := NewContext(httptest.NewRecorder(), req)
+	ctx.PathParams = map[string]string{"id": "x"}
+
+	var q bindQuery
+	err := ctx.Bind(&q)
+
+	var he *HTTPError
+	require.True(t, errors.As(err, &he))
+	assert.Equal(t, http.StatusBadRequest, he.Status)
+
+	var errs BindErrors
+	require.True(t, errors.As(err, &errs))
+
+	fields := make([]string, 0, len(errs))
+	for _, e := range errs {
+		fields = append(fields, e.Source+":"+e.Field)
+	}
+	assert.ElementsMatch(t, []string{"query:Page", "path:ID", "query:Filter.Status"}, fields)
+
+	assert.Error(t, ctx.Bind(q))
+}
@@ -0,0 +1,524 @@
+package web
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 常用的媒体类型
+const (
+	MIMEJSON      = "application/json"
+	MIMEXML       = "application/xml"
+	MIMETextXML   = "text/xml"
+	MIMEForm      = "application/x-www-form-urlencoded"
+	MIMEText      = "text/plain"
+	MIMEMultipart = "multipart/form-data"
+)
+
+var (
+	errUnsupportedMediaType = NewHTTPError(http.StatusUnsupportedMediaType, "unsupported_media_type", "不支持的请求体类型")
+	errNotAcceptable        = NewHTTPError(http.StatusNotAcceptable, "not_acceptable", "无法提供客户端可以接受的响应类型")
+	errDecodeFailed         = NewHTTPError(http.StatusBadRequest, "decode_failed", "请求体格式不合法")
+
+	errTrailingData      = errors.New("web: 请求体在 JSON 之后还有多余的数据")
+	errJSONCodecNotFound = errors.New("web: 没有注册 application/json 的编解码器")
+	errXMLCodecNotFound  = errors.New("web: 没有注册 application/xml 的编解码器")
+)
+
+// 反序列化请求体失败时返回的错误，原始的错误可以通过 errors.As 获取
+var (
+	// ErrSyntax 请求体语法错误，例如 JSON 不完整
+	ErrSyntax = NewHTTPError(http.StatusBadRequest, "syntax_error", "请求体语法错误")
+	// ErrFieldType 请求体中字段的类型和结构体不匹配
+	ErrFieldType = NewHTTPError(http.StatusBadRequest, "type_error", "请求体字段类型不匹配")
+	// ErrUnknownField 请求体中包含结构体没有的字段，需要开启 JSONCodec.DisallowUnknownFields
+	ErrUnknownField = NewHTTPError(http.StatusBadRequest, "unknown_field", "请求体包含未知字段")
+)
+
+// Codec 负责某一种媒体类型的序列化和反序列化
+type Codec interface {
+	// Decode 将 r 中的数据反序列化到 val 上
+	Decode(r io.Reader, val any) error
+	// Encode 将 val 序列化之后写入 w
+	Encode(w io.Writer, val any) error
+}
+
+// DefaultCodecs 没有通过 ServerWithCodecs 设置时使用的编解码器
+var DefaultCodecs = NewCodecRegistry()
+
+// ServerWithCodecs 设置 Bind、BindJSON、WriteJSON 以及 Negotiate 使用的编解码器
+func ServerWithCodecs(r *CodecRegistry) Option {
+	return func(httpServer *DefaultHttpServer) {
+		httpServer.codecs = r
+	}
+}
+
+// CodecRegistry 按照媒体类型管理编解码器
+// 内置 JSON、XML、表单以及纯文本，可以注册其他的编解码器，例如 protobuf
+type CodecRegistry struct {
+	mu     sync.RWMutex
+	codecs map[string]Codec
+	// types 按照注册顺序保存媒体类型，客户端没有偏好时优先使用先注册的
+	types []string
+}
+
+func NewCodecRegistry() *CodecRegistry {
+	r := &CodecRegistry{
+		codecs: map[string]Codec{},
+	}
+
+	r.Register(MIMEJSON, JSONCodec{})
+	r.Register(MIMEXML, XMLCodec{})
+	r.Register(MIMETextXML, XMLCodec{})
+	r.Register(MIMEForm, FormCodec{})
+	r.Register(MIMEText, TextCodec{})
+
+	return r
+}
+
+// Register 注册编解码器，同一个媒体类型重复注册会覆盖之前的
+func (r *CodecRegistry) Register(mediaType string, c Codec) {
+	mediaType = strings.ToLower(mediaType)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.codecs[mediaType]; !ok {
+		r.types = append(r.types, mediaType)
+	}
+	r.codecs[mediaType] = c
+}
+
+// Lookup 获取媒体类型对应的编解码器，contentType 可以带有参数，例如 charset
+func (r *CodecRegistry) Lookup(contentType string) (Codec, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c, ok := r.codecs[mediaType]
+	return c, ok
+}
+
+// Negotiate 根据 Accept 请求头选择编解码器，返回选中的媒体类型
+// Accept 为空时选择第一个注册的编解码器
+func (r *CodecRegistry) Negotiate(accept string) (string, Codec, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.types) == 0 {
+		return "", nil, false
+	}
+
+	if strings.TrimSpace(accept) == "" {
+		return r.types[0], r.codecs[r.types[0]], true
+	}
+
+	ranges := parseAccept(accept)
+
+	var (
+		best  string
+		bestQ float64
+	)
+
+	for _, typ := range r.types {
+		q := acceptQuality(ranges, typ)
+		if q > bestQ {
+			best, bestQ = typ, q
+		}
+	}
+
+	if best == "" {
+		return "", nil, false
+	}
+
+	return best, r.codecs[best], true
+}
+
+// acceptRange Accept 请求头中的一项
+type acceptRange struct {
+	typ     string
+	subtype string
+	q       float64
+}
+
+// parseAccept 解析 Accept 请求头，按照具体程度从高到低排序
+func parseAccept(accept string) []acceptRange {
+	var res []acceptRange
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		typ, subtype, ok := strings.Cut(mediaType, "/")
+		if !ok {
+			continue
+		}
+
+		q := 1.0
+		if qs, ok := params["q"]; ok {
+			q, err = strconv.ParseFloat(qs, 64)
+			if err != nil || q < 0 || q > 1 {
+				continue
+			}
+		}
+
+		res = append(res, acceptRange{typ: typ, subtype: subtype, q: q})
+	}
+
+	// 更具体的范围优先，例如 text/html 优先于 text/*，text/* 优先于 */*
+	sort.SliceStable(res, func(i, j int) bool {
+		return specificity(res[i]) > specificity(res[j])
+	})
+
+	return res
+}
+
+func specificity(r acceptRange) int {
+	switch {
+	case r.typ == "*":
+		return 0
+	case r.subtype == "*":
+		return 1
+	default:
+		return 2
+	}
+}
+
+// acceptQuality 返回媒体类型在 Accept 中的权重，取最具体的匹配项
+func acceptQuality(ranges []acceptRange, mediaType string) float64 {
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+
+	for _, r := range ranges {
+		if (r.typ == "*" || r.typ == typ) && (r.subtype == "*" || r.subtype == subtype) {
+			return r.q
+		}
+	}
+
+	return 0
+}
+
+// JSONCodec 使用 encoding/json 进行编解码，默认的行为和 encoding/json 一致
+// 需要严格校验请求体时可以重新注册：
+//
+//	codecs.Register(web.MIMEJSON, web.JSONCodec{DisallowUnknownFields: true, DisallowTrailingData: true})
+type JSONCodec struct {
+	// DisallowUnknownFields 请求体中包含结构体没有的字段时返回 ErrUnknownField
+	DisallowUnknownFields bool
+	// UseNumber 反序列化到 any 上的数字使用 json.Number，避免大整数丢失精度
+	UseNumber bool
+	// DisallowTrailingData JSON 之后还有其他数据时返回 ErrSyntax
+	DisallowTrailingData bool
+}
+
+func (c JSONCodec) Decode(r io.Reader, val any) error {
+	dec := json.NewDecoder(r)
+	if c.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	if c.UseNumber {
+		dec.UseNumber()
+	}
+
+	if err := dec.Decode(val); err != nil {
+		return err
+	}
+
+	if c.DisallowTrailingData {
+		if _, err := dec.Token(); err != io.EOF {
+			return errTrailingData
+		}
+	}
+
+	return nil
+}
+
+func (JSONCodec) Encode(w io.Writer, val any) error {
+	data, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// XMLCodec 使用 encoding/xml 进行编解码
+type XMLCodec struct{}
+
+func (XMLCodec) Decode(r io.Reader, val any) error {
+	return xml.NewDecoder(r).Decode(val)
+}
+
+func (XMLCodec) Encode(w io.Writer, val any) error {
+	return xml.NewEncoder(w).Encode(val)
+}
+
+// FormCodec 表单编解码
+// 反序列化时按照 form 标签绑定到结构体上，也支持 *url.Values
+// 序列化时支持 url.Values、map[string][]string 和 map[string]string
+type FormCodec struct{}
+
+func (FormCodec) Decode(r io.Reader, val any) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return err
+	}
+
+	if v, ok := val.(*url.Values); ok {
+		*v = values
+		return nil
+	}
+
+	rv := reflect.ValueOf(val)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("web: 表单只能反序列化到结构体指针或者 *url.Values 上")
+	}
+
+	fields, err := cachedFields(rv.Elem().Type())
+	if err != nil {
+		return err
+	}
+
+	var errs BindErrors
+	for _, f := range fields {
+		name, ok := f.names["form"]
+		if !ok || len(values[name]) == 0 {
+			continue
+		}
+		if err = setField(rv.Elem().FieldByIndex(f.index), values[name], f.timeFormat); err != nil {
+			errs = append(errs, &BindError{
+				Field:  f.path,
+				Source: "form",
+				Name:   name,
+				Value:  strings.Join(values[name], ","),
+				Err:    err,
+			})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (FormCodec) Encode(w io.Writer, val any) error {
+	var values url.Values
+
+	switch v := val.(type) {
+	case url.Values:
+		values = v
+	case map[string][]string:
+		values = v
+	case map[string]string:
+		values = make(url.Values, len(v))
+		for k, s := range v {
+			values.Set(k, s)
+		}
+	default:
+		return fmt.Errorf("web: 不支持将 %T 序列化为表单", val)
+	}
+
+	_, err := io.WriteString(w, values.Encode())
+	return err
+}
+
+// TextCodec 纯文本编解码
+// 反序列化支持 *string、*[]byte 以及 encoding.TextUnmarshaler
+// 序列化支持 string、[]byte、encoding.TextMarshaler、fmt.Stringer，其余类型使用 fmt.Fprint
+type TextCodec struct{}
+
+func (TextCodec) Decode(r io.Reader, val any) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	switch v := val.(type) {
+	case *string:
+		*v = string(data)
+	case *[]byte:
+		*v = data
+	case encoding.TextUnmarshaler:
+		return v.UnmarshalText(data)
+	default:
+		return fmt.Errorf("web: 不支持将纯文本反序列化到 %T 上", val)
+	}
+
+	return nil
+}
+
+func (TextCodec) Encode(w io.Writer, val any) error {
+	var err error
+
+	switch v := val.(type) {
+	case string:
+		_, err = io.WriteString(w, v)
+	case []byte:
+		_, err = w.Write(v)
+	case encoding.TextMarshaler:
+		var data []byte
+		if data, err = v.MarshalText(); err == nil {
+			_, err = w.Write(data)
+		}
+	default:
+		_, err = fmt.Fprint(w, val)
+	}
+
+	return err
+}
+
+func (c *Context) codecs() *CodecRegistry {
+	if c.srv != nil && c.srv.codecs != nil {
+		return c.srv.codecs
+	}
+	return DefaultCodecs
+}
+
+// hasBody 判断请求是否携带了请求体
+// 长度未知时，例如 HTTP/2 没有 Content-Length 的请求，先读取一个字节判断请求体是否为空
+func (c *Context) hasBody() bool {
+	body := c.Req.Body
+	if body == nil || body == http.NoBody {
+		return false
+	}
+	if c.Req.ContentLength >= 0 {
+		return c.Req.ContentLength > 0
+	}
+
+	var b [1]byte
+	n, err := io.ReadFull(body, b[:])
+	if n == 0 {
+		// 读取出错时交给反序列化处理，让错误可以返回给调用方
+		return err != io.EOF
+	}
+
+	c.Req.Body = peekedBody{
+		Reader: io.MultiReader(bytes.NewReader(b[:n]), body),
+		Closer: body,
+	}
+	return true
+}
+
+// peekedBody 补上 hasBody 中读取的字节
+type peekedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// decodeBody 根据 Content-Type 选择编解码器反序列化请求体
+// 表单由 Bind 中的 form 标签处理，这里跳过
+func (c *Context) decodeBody(dst any) error {
+	if !c.hasBody() {
+		return nil
+	}
+
+	contentType := c.Req.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == MIMEForm || mediaType == MIMEMultipart {
+		return nil
+	}
+
+	codec, ok := c.codecs().Lookup(contentType)
+	if !ok {
+		return errUnsupportedMediaType
+	}
+
+	if err := codec.Decode(c.Req.Body, dst); err != nil {
+		return decodeError(err)
+	}
+
+	return nil
+}
+
+// decodeError 将编解码器返回的错误转换为对应的 HTTPError
+func decodeError(err error) error {
+	var (
+		he        *HTTPError
+		bindErrs  BindErrors
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+		xmlErr    *xml.SyntaxError
+	)
+
+	switch {
+	case errors.As(err, &he):
+		// 读取请求体时返回的错误，例如 ErrBodyTooLarge
+		return err
+	case errors.As(err, &bindErrs):
+		return errBindFailed.WithError(err)
+	case errors.As(err, &syntaxErr), errors.As(err, &xmlErr),
+		errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, errTrailingData):
+		return ErrSyntax.WithError(err)
+	case errors.As(err, &typeErr):
+		return ErrFieldType.WithError(err)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json 没有为未知字段定义错误类型
+		return ErrUnknownField.WithError(err)
+	default:
+		return errDecodeFailed.WithError(err)
+	}
+}
+
+// Negotiate 根据 Accept 请求头选择编解码器输出 val
+// 没有客户端可以接受的类型时返回 406 对应的 HTTPError
+func (c *Context) Negotiate(status int, val any) error {
+	mediaType, codec, ok := c.codecs().Negotiate(c.Req.Header.Get("Accept"))
+	if !ok {
+		return errNotAcceptable
+	}
+
+	var buf bytes.Buffer
+	if err := codec.Encode(&buf, val); err != nil {
+		return err
+	}
+
+	if strings.HasPrefix(mediaType, "text/") {
+		mediaType += "; charset=utf-8"
+	}
+
+	header := c.Resp.Header()
+	header.Set("Content-Type", mediaType)
+	addVary(header, "Accept")
+
+	c.RespStatus = status
+	c.RespData = buf.Bytes()
+
+	return nil
+}
+
+// addVary 向 Vary 中添加 field，已经存在时不重复添加
+func addVary(header http.Header, field string) {
+	for _, line := range header.Values("Vary") {
+		for _, val := range strings.Split(line, ",") {
+			val = strings.TrimSpace(val)
+			if val == "*" || strings.EqualFold(val, field) {
+				return
+			}
+		}
+	}
+	header.Add("Vary", field)
+}
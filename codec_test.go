@@ -0,0 +1,119 @@
+package web
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodecRegistry_Negotiate(t *testing.T) {
+	r := NewCodecRegistry()
+
+	testCases := []struct {
+		name   string
+		accept string
+		want   string
+		ok     bool
+	}{
+		{name: "empty", accept: "", want: MIMEJSON, ok: true},
+		{name: "exact", accept: "application/xml", want: MIMEXML, ok: true},
+		{name: "wildcard", accept: "*/*", want: MIMEJSON, ok: true},
+		{name: "quality", accept: "application/json;q=0.5, text/plain", want: MIMEText, ok: true},
+		{name: "specific over range", accept: "text/*;q=0.9, text/plain;q=0.1", want: MIMETextXML, ok: true},
+		{name: "excluded", accept: "application/json;q=0, */*;q=0.1", want: MIMEXML, ok: true},
+		{name: "not acceptable", accept: "image/png", ok: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, _, ok := r.Negotiate(tc.accept)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+type codecUser struct {
+	ID   int64  `path:"id" xml:"-"`
+	Name string `json:"name" xml:"name"`
+}
+
+func TestContext_BindBody(t *testing.T) {
+	testCases := []struct {
+		name        string
+		contentType string
+		body        string
+		// unknownLength 模拟 HTTP/2 等没有 Content-Length 的请求
+		unknownLength bool
+		want          codecUser
+		wantStatus    int
+	}{
+		{name: "json", contentType: "application/json; charset=utf-8", body: `{"name":"tom"}`, want: codecUser{ID: 12, Name: "tom"}},
+		{name: "xml", contentType: "application/xml", body: `<user><name>tom</name></user>`, want: codecUser{ID: 12, Name: "tom"}},
+		{name: "unsupported", contentType: "application/msgpack", body: "x", wantStatus: http.StatusUnsupportedMediaType},
+		{name: "malformed", contentType: "application/json", body: "{", wantStatus: http.StatusBadRequest},
+		{name: "unknown length", contentType: "application/json", body: `{"name":"tom"}`, unknownLength: true, want: codecUser{ID: 12, Name: "tom"}},
+		{name: "unknown length empty", contentType: "application/json", unknownLength: true, want: codecUser{ID: 12}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/user/12", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", tc.contentType)
+			if tc.unknownLength {
+				req.Body = io.NopCloser(strings.NewReader(tc.body))
+				req.ContentLength = -1
+			}
+			ctx := NewContext(httptest.NewRecorder(), req)
+			ctx.PathParams = map[string]string{"id": "12"}
+
+			var u codecUser
+			err := ctx.Bind(&u)
+			if tc.wantStatus != 0 {
+				var he *HTTPError
+				require.True(t, errors.As(err, &he))
+				assert.Equal(t, tc.wantStatus, he.Status)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, u)
+		})
+	}
+}
+
+func TestContext_Negotiate(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "text/plain")
+	ctx := NewContext(httptest.NewRecorder(), req)
+
+	require.NoError(t, ctx.Negotiate(http.StatusCreated, "hello"))
+	assert.Equal(t, http.StatusCreated, ctx.RespStatus)
+	assert.Equal(t, "hello", string(ctx.RespData))
+	assert.Equal(t, "text/plain; charset=utf-8", ctx.Resp.Header().Get("Content-Type"))
+	assert.Equal(t, "Accept", ctx.Resp.Header().Get("Vary"))
+
+	// 多次协商时 Vary 不重复
+	require.NoError(t, ctx.Negotiate(http.StatusCreated, "hello"))
+	assert.Equal(t, []string{"Accept"}, ctx.Resp.Header().Values("Vary"))
+
+	req.Header.Set("Accept", "image/png")
+	assert.ErrorIs(t, ctx.Negotiate(http.StatusOK, "hello"), errNotAcceptable)
+}
+
+func TestContext_CodecNotFound(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"tom"}`))
+	ctx := NewContext(httptest.NewRecorder(), req)
+	ctx.srv = &DefaultHttpServer{codecs: &CodecRegistry{codecs: map[string]Codec{}}}
+
+	var u codecUser
+	assert.ErrorIs(t, ctx.BindJSON(&u), errJSONCodecNotFound)
+	assert.ErrorIs(t, ctx.WriteJSON(http.StatusOK, u), errJSONCodecNotFound)
+	// 服务端没有注册编解码器，不是客户端的问题
+	assert.ErrorIs(t, ctx.WriteXML(http.StatusOK, u), errXMLCodecNotFound)
+}
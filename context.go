@@ -1,27 +1,19 @@
 package web
 
 import (
-	"encoding/json"
+	"bytes"
+	"context"
 	"errors"
 	"net/http"
 	"net/url"
 	"reflect"
-	"strconv"
+	"time"
 )
 
-type StringValue struct {
-	data string
-	err  error
-}
-
-func (s StringValue) AsInt64() (int64, error) {
-	if s.err != nil {
-		return 0, s.err
-	}
-
-	return strconv.ParseInt(s.data, 10, 64)
-}
+var _ context.Context = &Context{}
 
+// Context 请求上下文
+// Context 会被复用，请求处理结束后不能再持有或者在其他 goroutine 中使用
 type Context struct {
 	Req        *http.Request
 	Resp       http.ResponseWriter
@@ -37,15 +29,115 @@ type Context struct {
 	T TemplateEngine
 
 	UserValues map[string]any
+
+	// values 通过 Set 保存的数据，键为 Key
+	values map[any]any
+
+	// writer 包装了原始的 http.ResponseWriter，Resp 指向它
+	writer responseWriter
+	stream StreamWriter
+
+	// srv 处理当前请求的 Server，用于获取 Server 级别的配置
+	srv *DefaultHttpServer
 }
 
-func (c *Context) Render(tplName string, data any) error {
+// NewContext 创建一个不属于任何 Server 的 Context
+// 一般用于测试中直接调用 HandleFunc，调用结束后需要调用 WriteResponse 输出响应
+func NewContext(resp http.ResponseWriter, req *http.Request) *Context {
+	c := &Context{
+		Req: req,
+	}
+	c.Resp = c.writer.reset(resp, c)
+	return c
+}
+
+// WriteResponse 输出 RespStatus 和 RespData，RespStatus 为0时按照200输出
+// 业务逻辑已经直接输出了响应，例如流式输出、http.ServeFile，或者连接已经被接管时，不再输出
+func (c *Context) WriteResponse() {
+	if c.writer.committed() {
+		return
+	}
+
+	status := c.RespStatus
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	c.Resp.WriteHeader(status)
+
+	if len(c.RespData) > 0 {
+		_, _ = c.Resp.Write(c.RespData)
+	}
+}
+
+// reset 重置 Context，以便放回池中复用
+func (c *Context) reset() {
+	*c = Context{}
+}
+
+// doneCtx 已经被取消的 context，Context 被重置之后使用
+var doneCtx = func() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}()
+
+// reqContext 返回 Req.Context()，Context 被重置之后返回已经取消的 context
+func (c *Context) reqContext() context.Context {
+	if c.Req == nil {
+		return doneCtx
+	}
+	return c.Req.Context()
+}
+
+// Deadline 返回请求的截止时间，以下 context.Context 的方法都委托给 Req.Context()
+// 因此在 HandleFunc 中可以直接把 Context 传给同步调用的函数，请求结束或者客户端断开时会被取消
+// Context 会被复用，可能被保留下来的地方，例如 goroutine、Session 的 Store 等，需要传 Req.Context()
+func (c *Context) Deadline() (time.Time, bool) {
+	return c.reqContext().Deadline()
+}
+
+func (c *Context) Done() <-chan struct{} {
+	return c.reqContext().Done()
+}
+
+func (c *Context) Err() error {
+	return c.reqContext().Err()
+}
 
-	ctx := c.Req.Context()
+// contextKey 通过 Value 获取 *Context 本身，用于从包装过的 context.Context 中取出 *Context
+type contextKey struct{}
+
+// contextFrom 从 ctx 中取出 *Context，ctx 可以是 *Context 本身或者委托给它的 context.Context
+func contextFrom(ctx context.Context) (*Context, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	c, ok := ctx.Value(contextKey{}).(*Context)
+	return c, ok
+}
+
+// Value 依次从 Set 保存的数据、UserValues（key 为字符串时）以及 Req.Context() 中获取
+func (c *Context) Value(key any) any {
+	if _, ok := key.(contextKey); ok {
+		return c
+	}
+	if val, ok := c.values[key]; ok {
+		return val
+	}
+	if k, ok := key.(string); ok {
+		if val, ok := c.UserValues[k]; ok {
+			return val
+		}
+	}
+	return c.reqContext().Value(key)
+}
+
+func (c *Context) Render(tplName string, data any) error {
 
 	var err error
 
-	c.RespData, err = c.T.Render(ctx, tplName, data)
+	c.RespData, err = c.T.Render(c, tplName, data)
 
 	if err != nil {
 		c.RespStatus = http.StatusInternalServerError
@@ -59,15 +151,24 @@ func (c *Context) Render(tplName string, data any) error {
 }
 
 // BindJSON 将body的json数据绑定到传入参数类型上
+// 反序列化失败时返回 ErrSyntax、ErrFieldType、ErrUnknownField 或者 ErrBodyTooLarge
+// 绑定成功后根据 validate 标签进行校验
 func (c *Context) BindJSON(data any) error {
 	typ := reflect.TypeOf(data)
 	if typ.Kind() != reflect.Pointer {
 		return errors.New("传入类型必须为指针类型")
 	}
 
-	decoder := json.NewDecoder(c.Req.Body)
+	codec, ok := c.codecs().Lookup(MIMEJSON)
+	if !ok {
+		return errJSONCodecNotFound
+	}
+
+	if err := codec.Decode(c.Req.Body, data); err != nil {
+		return decodeError(err)
+	}
 
-	return decoder.Decode(data)
+	return c.validate(data)
 }
 
 func (c *Context) GetForm(key string) StringValue {
@@ -79,7 +180,7 @@ func (c *Context) GetForm(key string) StringValue {
 	data, ok := c.Req.Form[key]
 
 	if !ok {
-		return StringValue{"", errors.New("key不存在")}
+		return StringValue{"", errKeyNotFound}
 	}
 
 	return StringValue{
@@ -99,7 +200,7 @@ func (c *Context) GetQuery(key string) StringValue {
 	if !ok {
 		return StringValue{
 			data: "",
-			err:  errors.New("key不存在"),
+			err:  errKeyNotFound,
 		}
 	}
 
@@ -109,20 +210,62 @@ func (c *Context) GetQuery(key string) StringValue {
 	}
 }
 
+// GetFormAll 获取表单中 key 对应的所有值
+func (c *Context) GetFormAll(key string) StringValues {
+	if err := c.Req.ParseForm(); err != nil {
+		return StringValues{err: err}
+	}
+
+	data, ok := c.Req.Form[key]
+	if !ok {
+		return StringValues{err: errKeyNotFound}
+	}
+
+	return StringValues{data: data}
+}
+
+// GetQueryAll 获取查询参数中 key 对应的所有值
+func (c *Context) GetQueryAll(key string) StringValues {
+	if c.QueryData == nil {
+		c.QueryData = c.Req.URL.Query()
+	}
+
+	data, ok := c.QueryData[key]
+	if !ok {
+		return StringValues{err: errKeyNotFound}
+	}
+
+	return StringValues{data: data}
+}
+
+// PathValue 获取路径参数
+func (c *Context) PathValue(key string) StringValue {
+	data, ok := c.PathParams[key]
+	if !ok {
+		return StringValue{err: errKeyNotFound}
+	}
+
+	return StringValue{data: data}
+}
+
 func (c *Context) WriteJSONOK(resp any) error {
 	return c.WriteJSON(http.StatusOK, resp)
 }
 
 func (c *Context) WriteJSON(status int, resp any) error {
 
-	data, err := json.Marshal(resp)
+	codec, ok := c.codecs().Lookup(MIMEJSON)
+	if !ok {
+		return errJSONCodecNotFound
+	}
 
-	if err != nil {
+	var buf bytes.Buffer
+	if err := codec.Encode(&buf, resp); err != nil {
 		return err
 	}
 
 	c.RespStatus = status
-	c.RespData = data
+	c.RespData = buf.Bytes()
 
 	return nil
 }
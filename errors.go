@@ -0,0 +1,136 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	// ErrNotFound 没有匹配的路由
+	ErrNotFound = NewHTTPError(http.StatusNotFound, "not_found", "resource not found")
+	// ErrMethodNotAllowed 路由存在，但是不支持当前的请求方法
+	ErrMethodNotAllowed = NewHTTPError(http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+)
+
+// HTTPError 携带响应码的错误，ErrorHandler 会根据响应码输出响应
+type HTTPError struct {
+	// Status http 响应码
+	Status int
+	// Code 业务错误码，便于客户端区分错误类型
+	Code string
+	// Message 返回给客户端的错误信息
+	Message string
+	// Err 导致该错误的原始错误，不会返回给客户端
+	Err error
+}
+
+func NewHTTPError(status int, code, msg string) *HTTPError {
+	return &HTTPError{
+		Status:  status,
+		Code:    code,
+		Message: msg,
+	}
+}
+
+func (e *HTTPError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("web: %d %s: %s: %v", e.Status, e.Code, e.Message, e.Err)
+	}
+	return fmt.Sprintf("web: %d %s: %s", e.Status, e.Code, e.Message)
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// Is 响应码和错误码相同即认为是同一种错误，使得 WithError 之后依旧可以用 errors.Is 判断
+func (e *HTTPError) Is(target error) bool {
+	t, ok := target.(*HTTPError)
+	if !ok {
+		return false
+	}
+	return e.Status == t.Status && e.Code == t.Code
+}
+
+// WithError 返回一个携带原始错误的副本，不修改当前错误
+func (e *HTTPError) WithError(err error) *HTTPError {
+	res := *e
+	res.Err = err
+	return &res
+}
+
+// ErrHandleFunc 返回错误的业务处理逻辑，返回的错误交给 ErrorHandler 统一处理
+type ErrHandleFunc func(ctx *Context) error
+
+// HandleErr 将 ErrHandleFunc 转换为可以注册到路由上的 HandleFunc
+func HandleErr(h ErrHandleFunc) HandleFunc {
+	return func(ctx *Context) {
+		if err := h(ctx); err != nil {
+			ctx.HandleError(err)
+		}
+	}
+}
+
+// ErrorHandler 将错误转换为响应
+type ErrorHandler func(ctx *Context, err error)
+
+// ServerWithErrorHandler 设置统一的错误处理逻辑
+func ServerWithErrorHandler(h ErrorHandler) Option {
+	return func(httpServer *DefaultHttpServer) {
+		httpServer.errHandler = h
+	}
+}
+
+// DefaultErrorHandler 默认的错误处理逻辑
+// HTTPError 和 Problem 按照其响应码和错误信息输出，HTTPError 的信息会按照请求的语言翻译，其余错误一律按照 500 处理，避免泄露内部错误
+func DefaultErrorHandler(ctx *Context, err error) {
+	var p *Problem
+	if errors.As(err, &p) && p.Status != 0 {
+		ctx.RespStatus = p.Status
+		ctx.RespData = []byte(p.Detail)
+		return
+	}
+
+	var he *HTTPError
+	if errors.As(err, &he) {
+		ctx.RespStatus = he.Status
+		ctx.RespData = []byte(ctx.errorMessage(he))
+		return
+	}
+
+	ctx.RespStatus = http.StatusInternalServerError
+	ctx.RespData = []byte(http.StatusText(http.StatusInternalServerError))
+}
+
+// PanicError 业务逻辑 panic 时恢复出来的值，例如 recovery 中间件会将其交给 HandleError
+type PanicError struct {
+	Value any
+	// Stack panic 时的调用栈
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("web: panic: %v", e.Value)
+}
+
+// HandleError 将错误交给 Server 的 ErrorHandler 处理
+// 处理之前会触发 OnError 回调，错误中包含 PanicError 时还会触发 OnPanic 回调
+func (c *Context) HandleError(err error) {
+	h := DefaultErrorHandler
+
+	if c.srv != nil {
+		var pe *PanicError
+		if errors.As(err, &pe) {
+			c.srv.hooks.firePanic(c, pe.Value)
+		}
+
+		c.srv.hooks.fireError(c, err)
+
+		if c.srv.errHandler != nil {
+			h = c.srv.errHandler
+		}
+	}
+
+	h(c, err)
+}
@@ -1,7 +1,11 @@
 package web
 
 import (
+	"crypto/sha256"
+	"errors"
+	"hash"
 	"io"
+	"io/fs"
 	"mime/multipart"
 	"net/http"
 	"os"
@@ -11,9 +15,35 @@ import (
 	lru "github.com/hashicorp/golang-lru"
 )
 
+var (
+	errUploadFailed   = NewHTTPError(http.StatusInternalServerError, "upload_failed", "文件上传失败")
+	errMissingFile    = NewHTTPError(http.StatusBadRequest, "missing_file", "缺少上传的文件")
+	errDownloadFailed = NewHTTPError(http.StatusInternalServerError, "download_failed", "文件下载失败")
+	errFileNotFound   = NewHTTPError(http.StatusNotFound, "file_not_found", "文件不存在")
+)
+
 type FileUploader struct {
 	FileField string
 	PathFunc  func(header *multipart.FileHeader) string
+
+	// Streaming 为 true 时边读取请求体边写入目标文件，不会先缓存到内存或者临时文件
+	// 此时表单中所有名为 FileField 的文件都会被保存，PathFunc 收到的 header 中 Size 为 0
+	Streaming bool
+	// Hash 写入文件的同时计算摘要，默认为 sha256，只有设置了 OnUploaded 时才会计算
+	Hash func() hash.Hash
+	// OnUploaded 每个文件保存之后调用，返回的错误交给 HandleError 处理
+	OnUploaded func(ctx *Context, file UploadedFile) error
+}
+
+// UploadedFile 保存成功的文件
+type UploadedFile struct {
+	Field    string
+	Filename string
+	// Path 文件保存的路径
+	Path string
+	Size int64
+	// Hash 文件的摘要
+	Hash []byte
 }
 
 func (f *FileUploader) Handle() HandleFunc {
@@ -28,39 +58,136 @@ func (f *FileUploader) Handle() HandleFunc {
 		}
 	}
 
-	return func(ctx *Context) {
+	if f.Hash == nil {
+		f.Hash = sha256.New
+	}
 
-		// 取出表单中的文件
-		srcFile, header, err := ctx.Req.FormFile(f.FileField)
+	return func(ctx *Context) {
+		var err error
+		if f.Streaming {
+			err = f.stream(ctx)
+		} else {
+			err = f.buffered(ctx)
+		}
 
 		if err != nil {
-			ctx.RespStatus = http.StatusInternalServerError
-			ctx.RespData = []byte("文件上传失败")
-			return
+			ctx.HandleError(err)
 		}
+	}
+}
 
-		// 调用用户传入函数，获取问价存储路径
-		path := f.PathFunc(header)
+func (f *FileUploader) buffered(ctx *Context) error {
+	// 按照 Server 设置的内存阈值解析表单，再取出表单中的文件
+	// 表单不合法时 parseMultipart 返回的是 400 或者 413 对应的 HTTPError
+	if err := ctx.parseMultipart(); err != nil {
+		return uploadError(err)
+	}
 
-		// 打开目标文件，不存在则创建，存在则清空
-		destFile, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0o666)
+	srcFile, header, err := ctx.Req.FormFile(f.FileField)
 
-		if err != nil {
-			ctx.RespStatus = http.StatusInternalServerError
-			ctx.RespData = []byte("文件上传失败")
-			return
-		}
+	if errors.Is(err, http.ErrMissingFile) {
+		return errMissingFile.WithError(err)
+	}
 
-		// 将文件保存到指定路径
-		_, err = io.Copy(destFile, srcFile)
+	if err != nil {
+		return errUploadFailed.WithError(err)
+	}
 
-		if err != nil {
-			ctx.RespStatus = http.StatusInternalServerError
-			ctx.RespData = []byte("文件上传失败")
-			return
+	defer srcFile.Close()
+
+	return f.save(ctx, header, srcFile)
+}
+
+func (f *FileUploader) stream(ctx *Context) error {
+	cnt := 0
+
+	err := ctx.EachPart(func(part *multipart.Part) error {
+		if part.FormName() != f.FileField || part.FileName() == "" {
+			return nil
 		}
+		cnt++
+		return f.save(ctx, &multipart.FileHeader{Filename: part.FileName(), Header: part.Header}, bodyReader{part})
+	})
+
+	if err != nil {
+		return uploadError(err)
+	}
 
+	if cnt == 0 {
+		return errMissingFile.WithError(http.ErrMissingFile)
 	}
+
+	return nil
+}
+
+// save 将 src 写入 PathFunc 返回的路径，设置了 OnUploaded 时同时计算摘要
+func (f *FileUploader) save(ctx *Context, header *multipart.FileHeader, src io.Reader) error {
+	// 调用用户传入函数，获取文件存储路径
+	path := f.PathFunc(header)
+
+	// 打开目标文件，不存在则创建，存在则清空
+	destFile, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0o666)
+
+	if err != nil {
+		return errUploadFailed.WithError(err)
+	}
+
+	// 摘要只提供给 OnUploaded，没有设置时不需要计算
+	var (
+		dst io.Writer = destFile
+		h   hash.Hash
+	)
+	if f.OnUploaded != nil {
+		h = f.Hash()
+		dst = io.MultiWriter(destFile, h)
+	}
+
+	// 将文件保存到指定路径
+	n, err := io.Copy(dst, src)
+
+	if closeErr := destFile.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		// 不保留不完整的文件
+		_ = os.Remove(path)
+		return uploadError(err)
+	}
+
+	if f.OnUploaded == nil {
+		return nil
+	}
+
+	return f.OnUploaded(ctx, UploadedFile{
+		Field:    f.FileField,
+		Filename: header.Filename,
+		Path:     path,
+		Size:     n,
+		Hash:     h.Sum(nil),
+	})
+}
+
+// uploadError 读取请求体时的 HTTPError，例如 ErrBodyTooLarge，保持原样，其余错误按照上传失败处理
+func uploadError(err error) error {
+	var he *HTTPError
+	if errors.As(err, &he) {
+		return err
+	}
+	return errUploadFailed.WithError(err)
+}
+
+// bodyReader 直接读取请求体时，将读取的错误按照请求体不合法处理，和写入文件的错误区分开
+type bodyReader struct {
+	io.Reader
+}
+
+func (r bodyReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err != nil && err != io.EOF {
+		err = decodeError(err)
+	}
+	return n, err
 }
 
 type FileDownloader struct {
@@ -78,8 +205,7 @@ func (f *FileDownloader) Handle() HandleFunc {
 		query := ctx.GetQuery(f.FileField)
 
 		if query.err != nil {
-			ctx.RespStatus = http.StatusInternalServerError
-			ctx.RespData = []byte("文件下载失败")
+			ctx.HandleError(query.err)
 			return
 		}
 
@@ -87,6 +213,17 @@ func (f *FileDownloader) Handle() HandleFunc {
 
 		destPath = filepath.Join(f.Dir, destPath)
 
+		// 设置下载的响应头之前检查文件，避免把错误信息当作文件下载
+		info, err := os.Stat(destPath)
+		if errors.Is(err, fs.ErrNotExist) || (err == nil && info.IsDir()) {
+			ctx.HandleError(errFileNotFound.WithError(err))
+			return
+		}
+		if err != nil {
+			ctx.HandleError(errDownloadFailed.WithError(err))
+			return
+		}
+
 		fn := filepath.Base(destPath)
 
 		header := ctx.Resp.Header()
@@ -167,8 +304,7 @@ func (s *StaticResourceHandler) Handle(ctx *Context) {
 	fileName, ok := ctx.PathParams[s.FileParam]
 
 	if !ok {
-		ctx.RespStatus = http.StatusInternalServerError
-		ctx.RespData = []byte("文件不存在")
+		ctx.HandleError(errFileNotFound)
 		return
 	}
 
@@ -183,9 +319,13 @@ func (s *StaticResourceHandler) Handle(ctx *Context) {
 
 	file, err := os.ReadFile(path)
 
+	if errors.Is(err, fs.ErrNotExist) {
+		ctx.HandleError(errFileNotFound.WithError(err))
+		return
+	}
+
 	if err != nil {
-		ctx.RespStatus = http.StatusInternalServerError
-		ctx.RespData = []byte("文件不存在")
+		ctx.HandleError(errDownloadFailed.WithError(err))
 		return
 	}
 
@@ -0,0 +1,218 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+// FlashLevel flash 消息的级别，模板中可以作为样式名称使用
+type FlashLevel string
+
+const (
+	FlashInfo    FlashLevel = "info"
+	FlashSuccess FlashLevel = "success"
+	FlashWarning FlashLevel = "warning"
+	FlashError   FlashLevel = "error"
+)
+
+// Flash 只展示一次的消息，一般在 POST 之后重定向到 GET 页面时使用
+type Flash struct {
+	Level   FlashLevel `json:"level"`
+	Message string     `json:"message"`
+}
+
+// FlashStore 保存 flash 消息
+type FlashStore interface {
+	// Save 保存下一次请求需要展示的消息，同一个请求中多次调用时以最后一次为准
+	Save(ctx *Context, flashes []Flash) error
+	// Consume 取出之前的请求保存的消息并删除，没有消息时返回 nil
+	Consume(ctx *Context) ([]Flash, error)
+}
+
+// ServerWithFlashStore 设置 flash 消息的存储，使用 session 保存时可以使用 session.FlashStore
+// 没有设置时，如果通过 ServerWithSecureCookie 设置了密钥则使用 CookieFlashStore，否则 AddFlash 和 Flashes 返回错误
+// 设置为 CookieFlashStore 但是没有设置密钥时 Start 返回错误
+func ServerWithFlashStore(store FlashStore) Option {
+	return func(httpServer *DefaultHttpServer) {
+		httpServer.flashStore = store
+	}
+}
+
+var (
+	// pendingFlashKey 当前请求添加的消息
+	pendingFlashKey = NewKey[[]Flash]("web.flash.pending")
+	// consumedFlashKey 当前请求读取到的消息，保证同一个请求中多次读取的结果一致
+	consumedFlashKey = NewKey[[]Flash]("web.flash.consumed")
+)
+
+var errFlashStoreNotConfigured = errors.New("web: 没有通过 ServerWithFlashStore 设置 flash 消息的存储，" +
+	"使用默认的 CookieFlashStore 需要先通过 ServerWithSecureCookie 设置密钥")
+
+func (c *Context) flashStore() (FlashStore, error) {
+	if c.srv != nil && c.srv.flashStore != nil {
+		return c.srv.flashStore, nil
+	}
+	if c.srv == nil || c.srv.secureCookie == nil {
+		return nil, errFlashStoreNotConfigured
+	}
+	return CookieFlashStore{}, nil
+}
+
+// checkFlashStore 启动时检查 CookieFlashStore 依赖的密钥是否已经设置
+func (s *DefaultHttpServer) checkFlashStore() error {
+	switch s.flashStore.(type) {
+	case CookieFlashStore, *CookieFlashStore:
+		if s.secureCookie == nil {
+			return errors.New("web: CookieFlashStore 需要通过 ServerWithSecureCookie 设置密钥")
+		}
+	}
+	return nil
+}
+
+// AddFlash 添加一条在下一次请求中展示的消息
+func (c *Context) AddFlash(level FlashLevel, message string) error {
+	pending, _ := Get(c, pendingFlashKey)
+	pending = append(pending, Flash{Level: level, Message: message})
+
+	store, err := c.flashStore()
+	if err != nil {
+		return err
+	}
+
+	if err = store.Save(c, pending); err != nil {
+		return err
+	}
+
+	Set(c, pendingFlashKey, pending)
+	return nil
+}
+
+// Flashes 取出之前的请求添加的消息，取出之后消息会被删除
+// 同一个请求中多次调用返回相同的结果
+func (c *Context) Flashes() ([]Flash, error) {
+	if flashes, ok := Get(c, consumedFlashKey); ok {
+		return flashes, nil
+	}
+
+	store, err := c.flashStore()
+	if err != nil {
+		return nil, err
+	}
+
+	flashes, err := store.Consume(c)
+	if err != nil {
+		return nil, err
+	}
+
+	// 删除之前的消息时不能影响当前请求添加的消息
+	if pending, ok := Get(c, pendingFlashKey); ok {
+		if err = store.Save(c, pending); err != nil {
+			return nil, err
+		}
+	}
+
+	Set(c, consumedFlashKey, flashes)
+	return flashes, nil
+}
+
+// FlashFuncMap 模板中使用的 flashes 函数，配合 GoTemplateEngine.RequestFuncs 使用
+//
+//	tpl := template.New("").Funcs(web.FlashFuncMap(nil))
+//	engine := web.GoTemplateEngine{Tpl: tpl, RequestFuncs: []web.RequestFuncMap{web.FlashFuncMap}}
+//
+//	{{range flashes}}<div class="{{.Level}}">{{.Message}}</div>{{end}}
+//
+// 调用时 ctx 中没有 *Context 时 flashes 返回 nil
+func FlashFuncMap(ctx context.Context) template.FuncMap {
+	return template.FuncMap{
+		"flashes": func() ([]Flash, error) {
+			c, ok := contextFrom(ctx)
+			if !ok {
+				return nil, nil
+			}
+			return c.Flashes()
+		},
+	}
+}
+
+// CookieFlashStore 使用签名的 cookie 保存 flash 消息，需要通过 ServerWithSecureCookie 设置密钥
+type CookieFlashStore struct {
+	// Name cookie 的名称，默认为 _flash
+	Name string
+	// Path cookie 的路径，默认为 /
+	Path string
+}
+
+func (s CookieFlashStore) cookie(value string) *http.Cookie {
+	ck := &http.Cookie{
+		Name:     s.Name,
+		Value:    value,
+		Path:     s.Path,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if ck.Name == "" {
+		ck.Name = "_flash"
+	}
+	if ck.Path == "" {
+		ck.Path = "/"
+	}
+	return ck
+}
+
+func (s CookieFlashStore) Save(ctx *Context, flashes []Flash) error {
+	data, err := json.Marshal(flashes)
+	if err != nil {
+		return err
+	}
+
+	ck := s.cookie(string(data))
+	removeSetCookie(ctx.Resp.Header(), ck.Name)
+	return ctx.SetSignedCookie(ck)
+}
+
+func (s CookieFlashStore) Consume(ctx *Context) ([]Flash, error) {
+	ck := s.cookie("")
+
+	value, err := ctx.GetSignedCookie(ck.Name)
+	if errors.Is(err, http.ErrNoCookie) {
+		return nil, nil
+	}
+
+	// 无论校验是否通过都删除 cookie
+	ck.MaxAge = -1
+	removeSetCookie(ctx.Resp.Header(), ck.Name)
+	ctx.SetCookie(ck)
+
+	if errors.Is(err, ErrInvalidCookie) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var flashes []Flash
+	if err = json.Unmarshal([]byte(value), &flashes); err != nil {
+		return nil, nil
+	}
+	return flashes, nil
+}
+
+// removeSetCookie 删除响应中已经设置的同名 cookie，避免输出多个 Set-Cookie
+func removeSetCookie(header http.Header, name string) {
+	cookies := header.Values("Set-Cookie")
+	if len(cookies) == 0 {
+		return
+	}
+
+	header.Del("Set-Cookie")
+	for _, ck := range cookies {
+		if !strings.HasPrefix(ck, name+"=") {
+			header.Add("Set-Cookie", ck)
+		}
+	}
+}
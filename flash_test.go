@@ -0,0 +1,75 @@
+package web
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContext_Flash(t *testing.T) {
+	tpl, err := template.New("page").Funcs(FlashFuncMap(nil)).
+		Parse(`{{range flashes}}[{{.Level}}:{{.Message}}]{{end}}{{range flashes}}!{{end}}`)
+	require.NoError(t, err)
+
+	s := NewHttpServer("",
+		ServerWithSecureCookie(NewSecureCookie([]byte("secret"))),
+		ServerWithTemplateEngine(GoTemplateEngine{Tpl: tpl, RequestFuncs: []RequestFuncMap{FlashFuncMap}}),
+	)
+
+	s.Post("/save", HandleErr(func(ctx *Context) error {
+		if err := ctx.AddFlash(FlashSuccess, "已保存"); err != nil {
+			return err
+		}
+		if err := ctx.AddFlash(FlashWarning, "库存不足"); err != nil {
+			return err
+		}
+		return ctx.Redirect(http.StatusSeeOther, "/page")
+	}))
+	s.Get("/page", HandleErr(func(ctx *Context) error {
+		return ctx.Render("page", nil)
+	}))
+
+	resp := httptest.NewRecorder()
+	s.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/save", nil))
+	require.Equal(t, http.StatusSeeOther, resp.Code)
+
+	cookies := resp.Result().Cookies()
+	require.Len(t, cookies, 1)
+
+	req := httptest.NewRequest(http.MethodGet, "/page", nil)
+	req.AddCookie(cookies[0])
+	resp = httptest.NewRecorder()
+	s.ServeHTTP(resp, req)
+
+	// 同一个请求中多次读取的结果一致
+	assert.Equal(t, "[success:已保存][warning:库存不足]!!", resp.Body.String())
+
+	cookies = resp.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, -1, cookies[0].MaxAge)
+
+	resp = httptest.NewRecorder()
+	s.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/page", nil))
+	assert.Equal(t, "", resp.Body.String())
+}
+
+func TestContext_FlashStoreNotConfigured(t *testing.T) {
+	s := NewHttpServer("")
+	s.Post("/save", HandleErr(func(ctx *Context) error {
+		return ctx.AddFlash(FlashInfo, "hello")
+	}))
+
+	ctx := NewContext(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/save", nil))
+	ctx.srv = s.(*DefaultHttpServer)
+	assert.ErrorIs(t, ctx.AddFlash(FlashInfo, "hello"), errFlashStoreNotConfigured)
+	_, err := ctx.Flashes()
+	assert.ErrorIs(t, err, errFlashStoreNotConfigured)
+
+	// 显式设置 CookieFlashStore 但是没有设置密钥时启动失败
+	s = NewHttpServer("127.0.0.1:0", ServerWithFlashStore(CookieFlashStore{}))
+	assert.ErrorContains(t, s.Start(), "ServerWithSecureCookie")
+}
@@ -0,0 +1,32 @@
+package web
+
+import (
+	"os"
+	"time"
+)
+
+const (
+	// envListenerFD 子进程从该环境变量中获取继承的监听套接字的文件描述符
+	envListenerFD = "WEB_LISTENER_FD"
+	// envReadyFD 子进程通过该环境变量对应的文件描述符通知父进程已经就绪
+	envReadyFD = "WEB_READY_FD"
+)
+
+// ServerWithRestartSignal 设置触发平滑重启的信号，例如 syscall.SIGUSR2
+// 收到信号后，当前进程会将监听套接字传递给重新执行的子进程，
+// 子进程就绪后当前进程停止接收新连接，处理完已有请求后退出
+// 目前仅支持 Linux
+func ServerWithRestartSignal(sigs ...os.Signal) Option {
+	return func(httpServer *DefaultHttpServer) {
+		httpServer.restartSignals = sigs
+	}
+}
+
+// ServerWithRestartTimeout 设置平滑重启的超时时间
+// ready 为等待子进程就绪的最长时间，shutdown 为旧进程等待已有请求处理完成的最长时间
+func ServerWithRestartTimeout(ready, shutdown time.Duration) Option {
+	return func(httpServer *DefaultHttpServer) {
+		httpServer.readyTimeout = ready
+		httpServer.shutdownTimeout = shutdown
+	}
+}
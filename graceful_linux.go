@@ -0,0 +1,155 @@
+//go:build linux
+
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Restart 平滑重启
+// 将监听套接字传递给重新执行的子进程，等待子进程就绪后优雅关闭当前Server
+// 如果子进程启动失败或者超时未就绪，当前Server继续提供服务
+func (s *DefaultHttpServer) Restart() error {
+	s.mu.Lock()
+	l := s.listener
+	s.mu.Unlock()
+
+	if l == nil {
+		return errors.New("web: server 尚未启动")
+	}
+
+	fl, ok := l.(interface {
+		File() (*os.File, error)
+	})
+	if !ok {
+		return fmt.Errorf("web: 监听套接字 %T 不支持传递给子进程", l)
+	}
+
+	// File 返回的是复制出来的文件描述符，关闭它不影响当前监听
+	lf, err := fl.File()
+	if err != nil {
+		return err
+	}
+	defer lf.Close()
+
+	// 子进程通过管道通知父进程已经就绪
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	exe, err := os.Executable()
+	if err != nil {
+		_ = w.Close()
+		return err
+	}
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	// ExtraFiles 中的文件在子进程中的描述符从3开始
+	cmd.ExtraFiles = []*os.File{lf, w}
+	cmd.Env = append(childEnv(), envListenerFD+"=3", envReadyFD+"=4")
+
+	err = cmd.Start()
+	// 父进程不再需要写端，否则子进程异常退出时读端无法感知
+	_ = w.Close()
+	if err != nil {
+		return err
+	}
+
+	ready := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 1)
+		_, err := r.Read(buf)
+		ready <- err
+	}()
+
+	select {
+	case err = <-ready:
+		if err != nil {
+			_ = cmd.Wait()
+			return fmt.Errorf("web: 子进程未就绪即退出: %w", err)
+		}
+	case <-time.After(s.readyTimeout):
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return errors.New("web: 等待子进程就绪超时")
+	}
+
+	// 子进程接管监听套接字后独立运行
+	_ = cmd.Process.Release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+
+	return s.Shutdown(ctx)
+}
+
+// inheritedListener 获取父进程传递过来的监听套接字，没有则返回nil
+func inheritedListener() (net.Listener, error) {
+	fd, ok, err := lookupFD(envListenerFD)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	f := os.NewFile(fd, "listener")
+	defer f.Close()
+
+	// FileListener 会复制文件描述符，因此原来的文件可以关闭
+	return net.FileListener(f)
+}
+
+// notifyReady 通知父进程子进程已经就绪
+func notifyReady() error {
+	fd, ok, err := lookupFD(envReadyFD)
+	if err != nil || !ok {
+		return err
+	}
+
+	f := os.NewFile(fd, "ready")
+	defer f.Close()
+
+	_, err = f.Write([]byte{1})
+	return err
+}
+
+// lookupFD 从环境变量中解析文件描述符，解析后删除环境变量，避免再传递给下一代进程
+func lookupFD(key string) (uintptr, bool, error) {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		return 0, false, nil
+	}
+
+	_ = os.Unsetenv(key)
+
+	fd, err := strconv.ParseUint(val, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("web: 环境变量 %s 不是合法的文件描述符: %w", key, err)
+	}
+
+	return uintptr(fd), true, nil
+}
+
+// childEnv 返回传递给子进程的环境变量，去掉平滑重启相关的变量
+func childEnv() []string {
+	env := os.Environ()
+	res := make([]string, 0, len(env)+2)
+	for _, e := range env {
+		if strings.HasPrefix(e, envListenerFD+"=") || strings.HasPrefix(e, envReadyFD+"=") {
+			continue
+		}
+		res = append(res, e)
+	}
+	return res
+}
@@ -0,0 +1,117 @@
+//go:build linux
+
+package web
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupFD(t *testing.T) {
+	t.Setenv(envListenerFD, "7")
+
+	fd, ok, err := lookupFD(envListenerFD)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, uintptr(7), fd)
+
+	// 解析之后删除环境变量
+	_, exists := os.LookupEnv(envListenerFD)
+	assert.False(t, exists)
+
+	_, ok, err = lookupFD(envListenerFD)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	t.Setenv(envReadyFD, "abc")
+	_, ok, err = lookupFD(envReadyFD)
+	assert.Error(t, err)
+	assert.False(t, ok)
+}
+
+func TestChildEnv(t *testing.T) {
+	t.Setenv(envListenerFD, "3")
+	t.Setenv(envReadyFD, "4")
+	t.Setenv("WEB_TEST_KEEP", "1")
+
+	env := childEnv()
+
+	assert.Contains(t, env, "WEB_TEST_KEEP=1")
+	assert.NotContains(t, env, envListenerFD+"=3")
+	assert.NotContains(t, env, envReadyFD+"=4")
+}
+
+func startServer(t *testing.T, name string) (*DefaultHttpServer, chan error) {
+	s := NewHttpServer("127.0.0.1:0").(*DefaultHttpServer)
+	s.Get("/", func(ctx *Context) {
+		ctx.RespData = []byte(name)
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Start()
+	}()
+
+	require.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.listener != nil
+	}, time.Second, time.Millisecond)
+
+	return s, done
+}
+
+func TestDefaultHttpServer_ListenerHandoff(t *testing.T) {
+	old, oldDone := startServer(t, "old")
+	addr := old.listener.Addr().String()
+
+	// 模拟 Restart 传递给子进程的套接字，inheritedListener 会接管复制出来的文件描述符
+	f, err := old.listener.(*net.TCPListener).File()
+	require.NoError(t, err)
+	fd, err := syscall.Dup(int(f.Fd()))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	// 子进程通过管道通知父进程已经就绪
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer r.Close()
+	readyFD, err := syscall.Dup(int(w.Fd()))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	t.Setenv(envListenerFD, strconv.Itoa(fd))
+	t.Setenv(envReadyFD, strconv.Itoa(readyFD))
+
+	child, childDone := startServer(t, "child")
+	assert.Equal(t, addr, child.listener.Addr().String())
+
+	buf := make([]byte, 1)
+	_, err = r.Read(buf)
+	require.NoError(t, err)
+
+	// 父进程关闭之后，新的连接由子进程处理
+	require.NoError(t, old.Shutdown(context.Background()))
+	assert.NoError(t, <-oldDone)
+
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	resp, err := client.Get("http://" + addr + "/")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "child", string(body))
+
+	require.NoError(t, child.Shutdown(context.Background()))
+	assert.NoError(t, <-childDone)
+}
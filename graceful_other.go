@@ -0,0 +1,21 @@
+//go:build !linux
+
+package web
+
+import (
+	"errors"
+	"net"
+)
+
+// Restart 平滑重启，目前仅支持 Linux
+func (s *DefaultHttpServer) Restart() error {
+	return errors.New("web: 当前平台不支持平滑重启")
+}
+
+func inheritedListener() (net.Listener, error) {
+	return nil, nil
+}
+
+func notifyReady() error {
+	return nil
+}
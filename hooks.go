@@ -0,0 +1,63 @@
+package web
+
+// Hooks 请求生命周期的回调
+// 与中间件不同，回调只用于观察请求，不参与中间件的顺序，也不能中断请求
+// 回调需要在 Start 之前注册
+type Hooks interface {
+	// OnRequestStart 请求开始处理时回调，此时还没有进行路由匹配
+	OnRequestStart(fns ...func(ctx *Context))
+	// OnRouteMatched 路由匹配成功后回调，此时 PathParams 和 MatchedRoute 已经设置
+	OnRouteMatched(fns ...func(ctx *Context))
+	// OnResponseWritten 响应输出之后回调
+	OnResponseWritten(fns ...func(ctx *Context))
+	// OnPanic 业务逻辑 panic 时回调，包括被 recovery 中间件恢复的 panic
+	OnPanic(fns ...func(ctx *Context, v any))
+	// OnError 调用 Context.HandleError 处理错误时回调
+	OnError(fns ...func(ctx *Context, err error))
+}
+
+type hooks struct {
+	requestStart    []func(ctx *Context)
+	routeMatched    []func(ctx *Context)
+	responseWritten []func(ctx *Context)
+	panics          []func(ctx *Context, v any)
+	errors          []func(ctx *Context, err error)
+}
+
+func (s *DefaultHttpServer) OnRequestStart(fns ...func(ctx *Context)) {
+	s.hooks.requestStart = append(s.hooks.requestStart, fns...)
+}
+
+func (s *DefaultHttpServer) OnRouteMatched(fns ...func(ctx *Context)) {
+	s.hooks.routeMatched = append(s.hooks.routeMatched, fns...)
+}
+
+func (s *DefaultHttpServer) OnResponseWritten(fns ...func(ctx *Context)) {
+	s.hooks.responseWritten = append(s.hooks.responseWritten, fns...)
+}
+
+func (s *DefaultHttpServer) OnPanic(fns ...func(ctx *Context, v any)) {
+	s.hooks.panics = append(s.hooks.panics, fns...)
+}
+
+func (s *DefaultHttpServer) OnError(fns ...func(ctx *Context, err error)) {
+	s.hooks.errors = append(s.hooks.errors, fns...)
+}
+
+func fire(ctx *Context, fns []func(ctx *Context)) {
+	for _, fn := range fns {
+		fn(ctx)
+	}
+}
+
+func (h *hooks) firePanic(ctx *Context, v any) {
+	for _, fn := range h.panics {
+		fn(ctx, v)
+	}
+}
+
+func (h *hooks) fireError(ctx *Context, err error) {
+	for _, fn := range h.errors {
+		fn(ctx, err)
+	}
+}
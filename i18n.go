@@ -0,0 +1,165 @@
+package web
+
+import (
+	"context"
+	"html/template"
+
+	"github.com/uzziahlin/web/i18n"
+)
+
+// DefaultCatalog 没有通过 ServerWithCatalog 设置时使用的消息目录，包含框架内置的中文和英文消息
+// Accept-Language 没有匹配的语言时使用英文，需要其他默认语言时通过 ServerWithCatalog 设置，例如
+//
+//	catalog := i18n.NewCatalog("zh")
+//	web.RegisterBuiltinMessages(catalog)
+//	server := web.NewHttpServer(":8080", web.ServerWithCatalog(catalog))
+var DefaultCatalog = newDefaultCatalog()
+
+func newDefaultCatalog() *i18n.Catalog {
+	c := i18n.NewCatalog("en")
+	RegisterBuiltinMessages(c)
+	return c
+}
+
+// builtinMessages 框架内置的错误信息，键为 "error." 加上 HTTPError.Code
+// 包含 websocket 包的握手错误，没有翻译时使用 HTTPError.Message
+var builtinMessages = map[string]map[string]string{
+	"zh": {
+		"error.not_found":              "资源不存在",
+		"error.method_not_allowed":     "不支持的请求方法",
+		"error.key_not_found":          "key不存在",
+		"error.upload_failed":          "文件上传失败",
+		"error.missing_file":           "缺少上传的文件",
+		"error.download_failed":        "文件下载失败",
+		"error.file_not_found":         "文件不存在",
+		"error.bind_failed":            "请求参数不合法",
+		"error.validate_failed":        "请求参数校验失败",
+		"error.unsupported_media_type": "不支持的请求体类型",
+		"error.not_acceptable":         "无法提供客户端可以接受的响应类型",
+		"error.decode_failed":          "请求体格式不合法",
+		"error.syntax_error":           "请求体语法错误",
+		"error.type_error":             "请求体字段类型不匹配",
+		"error.unknown_field":          "请求体包含未知字段",
+		"error.body_too_large":         "请求体过大",
+		"error.file_too_large":         "上传的文件过大",
+
+		"error.websocket_method_not_allowed": "WebSocket 握手请求必须是 GET",
+		"error.websocket_not_upgrade":        "不是 WebSocket 升级请求",
+		"error.websocket_bad_version":        "不支持的 WebSocket 协议版本",
+		"error.websocket_bad_key":            "Sec-WebSocket-Key 不合法",
+		"error.websocket_origin_denied":      "Origin 不允许",
+		"error.websocket_session_failed":     "加载 session 失败",
+		"error.websocket_hijack_failed":      "接管连接失败",
+	},
+	"en": {
+		"error.not_found":              "resource not found",
+		"error.method_not_allowed":     "method not allowed",
+		"error.key_not_found":          "key not found",
+		"error.upload_failed":          "file upload failed",
+		"error.missing_file":           "no file was uploaded",
+		"error.download_failed":        "file download failed",
+		"error.file_not_found":         "file not found",
+		"error.bind_failed":            "invalid request parameters",
+		"error.validate_failed":        "request validation failed",
+		"error.unsupported_media_type": "unsupported media type",
+		"error.not_acceptable":         "no acceptable representation available",
+		"error.decode_failed":          "malformed request body",
+		"error.syntax_error":           "request body syntax error",
+		"error.type_error":             "request body field has the wrong type",
+		"error.unknown_field":          "request body contains unknown fields",
+		"error.body_too_large":         "request body too large",
+		"error.file_too_large":         "uploaded file too large",
+
+		"error.websocket_method_not_allowed": "websocket handshake must use GET",
+		"error.websocket_not_upgrade":        "not a websocket upgrade request",
+		"error.websocket_bad_version":        "unsupported websocket version",
+		"error.websocket_bad_key":            "invalid Sec-WebSocket-Key",
+		"error.websocket_origin_denied":      "origin not allowed",
+		"error.websocket_session_failed":     "failed to load session",
+		"error.websocket_hijack_failed":      "failed to take over the connection",
+	},
+}
+
+// RegisterBuiltinMessages 将框架内置的消息添加到 c 中，使用自定义的 Catalog 时调用
+// 已经存在的同名消息会被覆盖，因此需要在添加自己的消息之前调用
+func RegisterBuiltinMessages(c *i18n.Catalog) {
+	for lang, msgs := range builtinMessages {
+		for key, msg := range msgs {
+			c.Set(lang, key, msg)
+		}
+	}
+}
+
+// ServerWithCatalog 设置消息目录，用于 Translate 以及翻译框架内置的错误信息
+// HTTPError 的信息按照 "error." 加上 Code 作为键翻译，没有翻译时使用 HTTPError.Message
+func ServerWithCatalog(c *i18n.Catalog) Option {
+	return func(httpServer *DefaultHttpServer) {
+		httpServer.catalog = c
+	}
+}
+
+var languageKey = NewKey[string]("web.language")
+
+func (c *Context) catalog() *i18n.Catalog {
+	if c.srv != nil && c.srv.catalog != nil {
+		return c.srv.catalog
+	}
+	return DefaultCatalog
+}
+
+// Language 根据 Accept-Language 请求头选择的语言
+func (c *Context) Language() string {
+	if lang, ok := Get(c, languageKey); ok {
+		return lang
+	}
+
+	lang := c.catalog().Match(c.Req.Header.Get("Accept-Language"))
+	Set(c, languageKey, lang)
+	return lang
+}
+
+// Translate 使用请求的语言翻译消息，找不到消息时返回 key
+func (c *Context) Translate(key string, args ...any) string {
+	return c.catalog().Translate(c.Language(), key, args...)
+}
+
+// TranslatePlural 使用请求的语言翻译消息，根据 n 选择复数形式
+func (c *Context) TranslatePlural(key string, n int, args ...any) string {
+	return c.catalog().TranslatePlural(c.Language(), key, n, args...)
+}
+
+// errorMessage 使用请求的语言翻译 HTTPError 的信息
+func (c *Context) errorMessage(he *HTTPError) string {
+	if he.Code != "" {
+		if msg, ok := c.catalog().Lookup(c.Language(), "error."+he.Code); ok {
+			return msg
+		}
+	}
+	return he.Message
+}
+
+// TranslateFuncMap 模板中使用的翻译函数 t 和 tn，配合 GoTemplateEngine.RequestFuncs 使用
+//
+//	tpl := template.New("").Funcs(web.TranslateFuncMap(nil))
+//	engine := web.GoTemplateEngine{Tpl: tpl, RequestFuncs: []web.RequestFuncMap{web.TranslateFuncMap}}
+//
+// 调用时 ctx 中没有 *Context 时使用默认消息目录的默认语言
+func TranslateFuncMap(ctx context.Context) template.FuncMap {
+	return template.FuncMap{
+		"t": func(key string, args ...any) string {
+			catalog, lang := translateTarget(ctx)
+			return catalog.Translate(lang, key, args...)
+		},
+		"tn": func(key string, n int, args ...any) string {
+			catalog, lang := translateTarget(ctx)
+			return catalog.TranslatePlural(lang, key, n, args...)
+		},
+	}
+}
+
+func translateTarget(ctx context.Context) (*i18n.Catalog, string) {
+	if c, ok := contextFrom(ctx); ok {
+		return c.catalog(), c.Language()
+	}
+	return DefaultCatalog, DefaultCatalog.Fallback()
+}
@@ -0,0 +1,255 @@
+package i18n
+
+import (
+	"fmt"
+	"html/template"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Catalog 按照语言保存翻译好的消息
+//
+//	c := i18n.NewCatalog("zh")
+//	c.Set("en", "greeting", "Hello, %s")
+//	c.SetPlural("en", "files", map[i18n.Plural]string{i18n.One: "%d file", i18n.Other: "%d files"})
+//
+//	c.Translate("en", "greeting", "Tom")  // Hello, Tom
+//	c.TranslatePlural("en", "files", 2)   // 2 files
+//
+// 语言使用 BCP 47 的格式，例如 zh、en、en-US，不区分大小写
+type Catalog struct {
+	mu       sync.RWMutex
+	fallback string
+	// messages 语言 -> 消息的键 -> 复数形式 -> 消息
+	messages map[string]map[string]map[Plural]string
+	rules    map[string]PluralRule
+	// langs 按照添加的顺序保存所有的语言
+	langs []string
+}
+
+// NewCatalog 创建 Catalog，fallback 为找不到匹配的语言或者消息时使用的语言
+func NewCatalog(fallback string) *Catalog {
+	fallback = normalize(fallback)
+	return &Catalog{
+		fallback: fallback,
+		messages: map[string]map[string]map[Plural]string{},
+		rules:    map[string]PluralRule{},
+		langs:    []string{fallback},
+	}
+}
+
+// Fallback 返回默认的语言
+func (c *Catalog) Fallback() string {
+	return c.fallback
+}
+
+// Languages 返回所有的语言，第一个为默认的语言
+func (c *Catalog) Languages() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]string(nil), c.langs...)
+}
+
+// Set 设置消息，等同于只有 Other 一种形式的 SetPlural
+func (c *Catalog) Set(lang, key, msg string) {
+	c.SetPlural(lang, key, map[Plural]string{Other: msg})
+}
+
+// SetPlural 设置消息的复数形式，没有对应形式时使用 Other
+func (c *Catalog) SetPlural(lang, key string, forms map[Plural]string) {
+	lang = normalize(lang)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	msgs, ok := c.messages[lang]
+	if !ok {
+		msgs = map[string]map[Plural]string{}
+		c.messages[lang] = msgs
+		if lang != c.fallback {
+			c.langs = append(c.langs, lang)
+		}
+	}
+
+	copied := make(map[Plural]string, len(forms))
+	for form, msg := range forms {
+		copied[form] = msg
+	}
+	msgs[key] = copied
+}
+
+// SetPluralRule 设置语言的复数规则，覆盖内置的规则
+func (c *Catalog) SetPluralRule(lang string, rule PluralRule) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rules[normalize(lang)] = rule
+}
+
+// Match 根据 Accept-Language 请求头选择最合适的语言，没有匹配的语言时返回默认的语言
+// 依次尝试完全匹配，以及主语言相同的匹配，例如 en-US 可以匹配 en，zh 可以匹配 zh-CN
+func (c *Catalog) Match(acceptLanguage string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, tag := range parseAcceptLanguage(acceptLanguage) {
+		if tag == "*" {
+			return c.fallback
+		}
+		if _, ok := c.messages[tag]; ok {
+			return tag
+		}
+
+		base := baseOf(tag)
+		if _, ok := c.messages[base]; ok {
+			return base
+		}
+		for _, lang := range c.langs {
+			if baseOf(lang) == base {
+				return lang
+			}
+		}
+	}
+
+	return c.fallback
+}
+
+// Lookup 获取消息，依次尝试 lang、lang 的主语言以及默认的语言
+func (c *Catalog) Lookup(lang, key string) (string, bool) {
+	forms, ok := c.forms(lang, key)
+	if !ok {
+		return "", false
+	}
+	msg, ok := forms[Other]
+	return msg, ok
+}
+
+// Translate 翻译消息，args 不为空时按照 fmt.Sprintf 的格式化
+// 找不到消息时返回 key
+func (c *Catalog) Translate(lang, key string, args ...any) string {
+	msg, ok := c.Lookup(lang, key)
+	if !ok {
+		return key
+	}
+	return format(msg, args)
+}
+
+// TranslatePlural 根据 n 选择消息的复数形式，n 作为第一个参数参与格式化
+// 找不到消息时返回 key
+func (c *Catalog) TranslatePlural(lang, key string, n int, args ...any) string {
+	forms, ok := c.forms(lang, key)
+	if !ok {
+		return key
+	}
+
+	msg, ok := forms[c.rule(lang)(n)]
+	if !ok {
+		msg = forms[Other]
+	}
+
+	return format(msg, append([]any{n}, args...))
+}
+
+// FuncMap 模板中使用的翻译函数
+//
+//	{{t "greeting" .Name}}
+//	{{tn "files" .Count}}
+func (c *Catalog) FuncMap(lang string) template.FuncMap {
+	return template.FuncMap{
+		"t": func(key string, args ...any) string {
+			return c.Translate(lang, key, args...)
+		},
+		"tn": func(key string, n int, args ...any) string {
+			return c.TranslatePlural(lang, key, n, args...)
+		},
+	}
+}
+
+func (c *Catalog) forms(lang, key string) (map[Plural]string, bool) {
+	lang = normalize(lang)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, l := range []string{lang, baseOf(lang), c.fallback} {
+		if forms, ok := c.messages[l][key]; ok {
+			return forms, true
+		}
+	}
+	return nil, false
+}
+
+func (c *Catalog) rule(lang string) PluralRule {
+	lang = normalize(lang)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, l := range []string{lang, baseOf(lang)} {
+		if rule, ok := c.rules[l]; ok {
+			return rule
+		}
+		if rule, ok := builtinRules[l]; ok {
+			return rule
+		}
+	}
+	return oneOther
+}
+
+// format 消息中没有格式化的占位符时直接返回，避免输出 %!(EXTRA ...)
+func format(msg string, args []any) string {
+	if len(args) == 0 || !strings.Contains(msg, "%") {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+func normalize(lang string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(lang), "_", "-"))
+}
+
+func baseOf(lang string) string {
+	base, _, _ := strings.Cut(lang, "-")
+	return base
+}
+
+// parseAcceptLanguage 解析 Accept-Language，按照权重从高到低返回语言，权重为 0 的语言会被忽略
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = normalize(tag)
+		if tag == "" {
+			continue
+		}
+
+		q := 1.0
+		if name, val, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(name) == "q" {
+			var err error
+			if q, err = strconv.ParseFloat(strings.TrimSpace(val), 64); err != nil {
+				continue
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+
+		tags = append(tags, weighted{tag: tag, q: q})
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].q > tags[j].q
+	})
+
+	res := make([]string, 0, len(tags))
+	for _, t := range tags {
+		res = append(res, t.tag)
+	}
+	return res
+}
@@ -0,0 +1,66 @@
+package i18n
+
+import (
+	"bytes"
+	"html/template"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCatalog() *Catalog {
+	c := NewCatalog("zh")
+	c.Set("zh", "greeting", "你好，%s")
+	c.Set("en", "greeting", "Hello, %s")
+	c.Set("en-GB", "color", "colour")
+	c.Set("en", "color", "color")
+	c.SetPlural("en", "files", map[Plural]string{One: "%d file", Other: "%d files"})
+	c.SetPlural("ru", "files", map[Plural]string{One: "%d файл", Few: "%d файла", Many: "%d файлов"})
+	c.Set("zh", "files", "%d 个文件")
+	return c
+}
+
+func TestCatalog_Match(t *testing.T) {
+	c := newTestCatalog()
+
+	testCases := []struct {
+		accept string
+		want   string
+	}{
+		{accept: "", want: "zh"},
+		{accept: "en", want: "en"},
+		{accept: "en-US,en;q=0.9", want: "en"},
+		{accept: "EN_gb", want: "en-gb"},
+		{accept: "fr, ru;q=0.5", want: "ru"},
+		{accept: "de;q=0.8, en;q=0, *;q=0.1", want: "zh"},
+		{accept: "ja", want: "zh"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.accept, func(t *testing.T) {
+			assert.Equal(t, tc.want, c.Match(tc.accept))
+		})
+	}
+}
+
+func TestCatalog_Translate(t *testing.T) {
+	c := newTestCatalog()
+
+	assert.Equal(t, "Hello, Tom", c.Translate("en-US", "greeting", "Tom"))
+	assert.Equal(t, "你好，Tom", c.Translate("ja", "greeting", "Tom"))
+	assert.Equal(t, "colour", c.Translate("en-GB", "color"))
+	assert.Equal(t, "missing", c.Translate("en", "missing"))
+
+	assert.Equal(t, "1 file", c.TranslatePlural("en", "files", 1))
+	assert.Equal(t, "3 files", c.TranslatePlural("en", "files", 3))
+	assert.Equal(t, "3 个文件", c.TranslatePlural("zh", "files", 3))
+	assert.Equal(t, "21 файл", c.TranslatePlural("ru", "files", 21))
+	assert.Equal(t, "3 файла", c.TranslatePlural("ru", "files", 3))
+	assert.Equal(t, "11 файлов", c.TranslatePlural("ru", "files", 11))
+
+	tpl := template.Must(template.New("").Funcs(c.FuncMap("en")).Parse(`{{t "greeting" "Tom"}} / {{tn "files" 2}}`))
+	var buf bytes.Buffer
+	require.NoError(t, tpl.Execute(&buf, nil))
+	assert.Equal(t, "Hello, Tom / 2 files", buf.String())
+}
@@ -0,0 +1,69 @@
+package i18n
+
+// Plural CLDR 定义的复数形式
+type Plural string
+
+const (
+	Zero  Plural = "zero"
+	One   Plural = "one"
+	Two   Plural = "two"
+	Few   Plural = "few"
+	Many  Plural = "many"
+	Other Plural = "other"
+)
+
+// PluralRule 根据数量返回复数形式
+type PluralRule func(n int) Plural
+
+// builtinRules 常用语言的复数规则，没有列出的语言按照 oneOther 处理
+var builtinRules = map[string]PluralRule{
+	"zh": otherOnly,
+	"ja": otherOnly,
+	"ko": otherOnly,
+	"vi": otherOnly,
+	"th": otherOnly,
+	"en": oneOther,
+	"de": oneOther,
+	"es": oneOther,
+	"it": oneOther,
+	"nl": oneOther,
+	"pt": oneOther,
+	"fr": zeroOneOther,
+	"ru": eastSlavic,
+	"uk": eastSlavic,
+}
+
+func otherOnly(int) Plural {
+	return Other
+}
+
+func oneOther(n int) Plural {
+	if n == 1 {
+		return One
+	}
+	return Other
+}
+
+// zeroOneOther 0 和 1 都使用单数，例如法语
+func zeroOneOther(n int) Plural {
+	if n == 0 || n == 1 {
+		return One
+	}
+	return Other
+}
+
+// eastSlavic 俄语、乌克兰语的规则
+func eastSlavic(n int) Plural {
+	if n < 0 {
+		n = -n
+	}
+	mod10, mod100 := n%10, n%100
+	switch {
+	case mod10 == 1 && mod100 != 11:
+		return One
+	case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+		return Few
+	default:
+		return Many
+	}
+}
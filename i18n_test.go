@@ -0,0 +1,152 @@
+package web
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uzziahlin/web/i18n"
+)
+
+func TestContext_Translate(t *testing.T) {
+	catalog := i18n.NewCatalog("zh")
+	RegisterBuiltinMessages(catalog)
+	catalog.Set("zh", "welcome", "欢迎，%s")
+	catalog.Set("en", "welcome", "Welcome, %s")
+	catalog.Set("en", "error.upload_failed", "upload failed, please retry")
+
+	s := NewHttpServer("", ServerWithCatalog(catalog))
+	s.Get("/page", HandleErr(func(ctx *Context) error {
+		if _, err := ctx.GetQuery("id").AsInt64(); err != nil {
+			return err
+		}
+		return ctx.WriteString(http.StatusOK, ctx.Translate("welcome", "Tom"))
+	}))
+	s.Get("/upload", HandleErr(func(ctx *Context) error {
+		return errUploadFailed
+	}))
+
+	testCases := []struct {
+		name     string
+		target   string
+		lang     string
+		wantBody string
+	}{
+		{name: "translate zh", target: "/page?id=1", wantBody: "欢迎，Tom"},
+		{name: "translate en", target: "/page?id=1", lang: "en-US,en;q=0.9", wantBody: "Welcome, Tom"},
+		{name: "builtin zh", target: "/page", lang: "fr", wantBody: "key不存在"},
+		{name: "builtin en", target: "/page", lang: "en", wantBody: "key not found"},
+		{name: "overridden", target: "/upload", lang: "en", wantBody: "upload failed, please retry"},
+		{name: "not found en", target: "/missing", lang: "en", wantBody: "resource not found"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.target, nil)
+			if tc.lang != "" {
+				req.Header.Set("Accept-Language", tc.lang)
+			}
+			resp := httptest.NewRecorder()
+			s.ServeHTTP(resp, req)
+			assert.Equal(t, tc.wantBody, resp.Body.String())
+		})
+	}
+}
+
+func TestDefaultCatalog_Fallback(t *testing.T) {
+	s := NewHttpServer("")
+	s.Get("/page", HandleErr(func(ctx *Context) error {
+		_, err := ctx.GetQuery("id").AsInt64()
+		return err
+	}))
+
+	// 没有 Accept-Language 或者没有匹配的语言时使用英文
+	for _, lang := range []string{"", "fr", "zh-CN"} {
+		req := httptest.NewRequest(http.MethodGet, "/page", nil)
+		if lang != "" {
+			req.Header.Set("Accept-Language", lang)
+		}
+		resp := httptest.NewRecorder()
+		s.ServeHTTP(resp, req)
+
+		want := "key not found"
+		if lang == "zh-CN" {
+			want = "key不存在"
+		}
+		assert.Equal(t, want, resp.Body.String(), lang)
+	}
+}
+
+func TestBuiltinMessages(t *testing.T) {
+	// 每种语言都包含所有的内置消息
+	for key := range builtinMessages["en"] {
+		assert.Contains(t, builtinMessages["zh"], key)
+	}
+	for key := range builtinMessages["zh"] {
+		assert.Contains(t, builtinMessages["en"], key)
+	}
+}
+
+func TestGoTemplateEngine_RequestFuncs(t *testing.T) {
+	catalog := i18n.NewCatalog("en")
+	catalog.Set("zh", "hello", "你好")
+	catalog.Set("en", "hello", "hello")
+
+	tpl := template.Must(template.New("page").Funcs(TranslateFuncMap(nil)).Parse(`{{t "hello"}}`))
+	s := NewHttpServer("",
+		ServerWithCatalog(catalog),
+		ServerWithTemplateEngine(GoTemplateEngine{Tpl: tpl, RequestFuncs: []RequestFuncMap{TranslateFuncMap}}),
+	)
+	s.Get("/page", HandleErr(func(ctx *Context) error {
+		return ctx.Render("page", nil)
+	}))
+	s.Get("/other", HandleErr(func(ctx *Context) error {
+		return ctx.Render("other", nil)
+	}))
+
+	render := func(lang string) string {
+		req := httptest.NewRequest(http.MethodGet, "/page", nil)
+		req.Header.Set("Accept-Language", lang)
+		resp := httptest.NewRecorder()
+		s.ServeHTTP(resp, req)
+		return resp.Body.String()
+	}
+
+	assert.Equal(t, "你好", render("zh"))
+
+	// 第一次渲染之后解析的模板依旧可以使用
+	template.Must(tpl.New("other").Parse(`{{t "hello"}}!`))
+	req := httptest.NewRequest(http.MethodGet, "/other", nil)
+	req.Header.Set("Accept-Language", "zh")
+	resp := httptest.NewRecorder()
+	s.ServeHTTP(resp, req)
+	assert.Equal(t, "你好!", resp.Body.String())
+
+	// 共享 Tpl 的引擎使用各自的 RequestFuncs
+	engine := GoTemplateEngine{Tpl: tpl, RequestFuncs: []RequestFuncMap{func(ctx context.Context) template.FuncMap {
+		return template.FuncMap{"t": func(key string, args ...any) string { return "custom" }}
+	}}}
+	data, err := engine.Render(context.Background(), "page", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "custom", string(data))
+
+	// 并发渲染时每个请求使用自己的语言
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		lang, want := "zh", "你好"
+		if i%2 == 0 {
+			lang, want = "en", "hello"
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, want, render(lang))
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,55 @@
+package web
+
+import "fmt"
+
+// Key 请求级别数据的键，值的类型为 T
+// 每次调用 NewKey 都会得到一个新的键，即使名称和类型都相同，因此应该保存在包级别的变量中
+// 名称只用于调试输出，建议在名称中带上包名，例如 "session.session"
+//
+//	var userKey = web.NewKey[*User]("auth.user")
+//
+//	web.Set(ctx, userKey, user)
+//	user, ok := web.Get(ctx, userKey)
+type Key[T any] struct {
+	// k 按照指针比较，保证不同的 NewKey 调用得到的键互不冲突
+	k *keyImpl
+}
+
+type keyImpl struct {
+	name string
+}
+
+func NewKey[T any](name string) Key[T] {
+	return Key[T]{k: &keyImpl{name: name}}
+}
+
+func (k Key[T]) String() string {
+	var (
+		zero T
+		name string
+	)
+	if k.k != nil {
+		name = k.k.name
+	}
+	return fmt.Sprintf("web.Key[%T](%s)", zero, name)
+}
+
+// Set 将 val 保存到 Context 中，请求结束之后清空
+func Set[T any](ctx *Context, key Key[T], val T) {
+	if ctx.values == nil {
+		ctx.values = make(map[any]any, 1)
+	}
+	ctx.values[key] = val
+}
+
+// Get 获取 Set 保存的值，没有保存时返回 T 的零值和 false
+func Get[T any](ctx *Context, key Key[T]) (T, bool) {
+	val, ok := ctx.values[key]
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	// Set 保存的可能是值为 nil 的接口，此时断言失败，返回 T 的零值
+	v, _ := val.(T)
+	return v, true
+}
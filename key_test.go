@@ -0,0 +1,51 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKey(t *testing.T) {
+	ctx := NewContext(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	userKey := NewKey[string]("auth.user")
+	idKey := NewKey[int]("auth.user")
+
+	_, ok := Get(ctx, userKey)
+	assert.False(t, ok)
+
+	Set(ctx, userKey, "tom")
+	Set(ctx, idKey, 12)
+
+	user, ok := Get(ctx, userKey)
+	assert.True(t, ok)
+	assert.Equal(t, "tom", user)
+
+	// 名称相同，类型不同的 Key 互不影响
+	id, ok := Get(ctx, idKey)
+	assert.True(t, ok)
+	assert.Equal(t, 12, id)
+
+	// 同名同类型的 Key 也是不同的键，其他包创建的 Key 不会覆盖
+	_, ok = Get(ctx, NewKey[string]("auth.user"))
+	assert.False(t, ok)
+	user, _ = Get(ctx, userKey)
+	assert.Equal(t, "tom", user)
+
+	assert.Equal(t, "tom", ctx.Value(userKey))
+	assert.Equal(t, "web.Key[int](auth.user)", idKey.String())
+
+	// 值为 nil 的接口
+	errKey := NewKey[error]("auth.err")
+	Set(ctx, errKey, nil)
+	e, ok := Get(ctx, errKey)
+	assert.True(t, ok)
+	assert.Nil(t, e)
+
+	ctx.reset()
+	_, ok = Get(ctx, userKey)
+	assert.False(t, ok)
+}
@@ -0,0 +1,53 @@
+package web
+
+import (
+	"errors"
+	"io"
+	"net/http"
+)
+
+// ErrBodyTooLarge 请求体超过了大小限制
+var ErrBodyTooLarge = NewHTTPError(http.StatusRequestEntityTooLarge, "body_too_large", "请求体过大")
+
+// ServerWithBodyLimit 设置全局的请求体大小限制，单位为字节
+// 单个路由可以通过 LimitBody 或者 bodylimit 中间件设置不同的限制
+func ServerWithBodyLimit(n int64) Option {
+	return func(httpServer *DefaultHttpServer) {
+		httpServer.bodyLimit = n
+	}
+}
+
+// LimitBody 限制请求体的大小，读取超过 n 字节时返回 ErrBodyTooLarge，响应之后连接会被关闭
+// 重复调用时以最后一次为准，因此路由上设置的限制会覆盖全局的限制，必须在读取请求体之前调用
+func (c *Context) LimitBody(n int64) {
+	body := c.Req.Body
+	if body == nil || body == http.NoBody {
+		return
+	}
+
+	if lb, ok := body.(*limitedBody); ok {
+		body = lb.orig
+	}
+
+	c.Req.Body = &limitedBody{
+		// 传入原始的 ResponseWriter，超过限制时 net/http 才能关闭连接
+		ReadCloser: http.MaxBytesReader(c.writer.ResponseWriter, body, n),
+		orig:       body,
+	}
+}
+
+// limitedBody 将 http.MaxBytesReader 超过限制时返回的错误转换为 ErrBodyTooLarge
+// 其他错误，例如客户端断开连接，原样返回
+type limitedBody struct {
+	io.ReadCloser
+	orig io.ReadCloser
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		return n, ErrBodyTooLarge.WithError(err)
+	}
+	return n, err
+}
@@ -0,0 +1,121 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_BodyLimit(t *testing.T) {
+	s := NewHttpServer("", ServerWithBodyLimit(8))
+
+	var got []string
+	handler := HandleErr(func(ctx *Context) error {
+		var data map[string]string
+		if err := ctx.BindJSON(&data); err != nil {
+			return err
+		}
+		got = append(got, data["name"])
+		return nil
+	})
+
+	s.Post("/small", handler)
+	s.Post("/large", handler)
+	s.Use(http.MethodPost, "/large", func(next HandleFunc) HandleFunc {
+		return func(ctx *Context) {
+			ctx.LimitBody(64)
+			next(ctx)
+		}
+	})
+
+	body := `{"name":"tom"}`
+
+	resp := httptest.NewRecorder()
+	s.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/small", strings.NewReader(body)))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.Code)
+
+	resp = httptest.NewRecorder()
+	s.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/large", strings.NewReader(body)))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, []string{"tom"}, got)
+}
+
+type brokenBody struct {
+	data string
+}
+
+func (b *brokenBody) Read(p []byte) (int, error) {
+	if b.data == "" {
+		return 0, io.ErrUnexpectedEOF
+	}
+	n := copy(p, b.data)
+	b.data = b.data[n:]
+	return n, nil
+}
+
+func (b *brokenBody) Close() error {
+	return nil
+}
+
+func TestContext_LimitBody(t *testing.T) {
+	// 正好读到限制的大小时连接断开，不是请求体过大
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Body = &brokenBody{data: "12345678"}
+	ctx := NewContext(httptest.NewRecorder(), req)
+	ctx.LimitBody(8)
+
+	_, err := io.ReadAll(ctx.Req.Body)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.False(t, errors.Is(err, ErrBodyTooLarge))
+
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("123456789"))
+	ctx = NewContext(httptest.NewRecorder(), req)
+	ctx.LimitBody(8)
+
+	_, err = io.ReadAll(ctx.Req.Body)
+	assert.ErrorIs(t, err, ErrBodyTooLarge)
+}
+
+func TestJSONCodec_Strict(t *testing.T) {
+	codecs := NewCodecRegistry()
+	codecs.Register(MIMEJSON, JSONCodec{DisallowUnknownFields: true, UseNumber: true, DisallowTrailingData: true})
+	s := NewHttpServer("", ServerWithCodecs(codecs))
+
+	testCases := []struct {
+		name    string
+		body    string
+		wantErr error
+	}{
+		{name: "ok", body: `{"name":"tom","age":18}`},
+		{name: "syntax", body: `{"name":`, wantErr: ErrSyntax},
+		{name: "trailing", body: `{"name":"tom"} {}`, wantErr: ErrSyntax},
+		{name: "type", body: `{"name":1}`, wantErr: ErrFieldType},
+		{name: "unknown", body: `{"nick":"tom"}`, wantErr: ErrUnknownField},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := NewContext(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body)))
+			ctx.srv = s.(*DefaultHttpServer)
+
+			var user struct {
+				Name string `json:"name"`
+				Age  any    `json:"age"`
+			}
+			err := ctx.BindJSON(&user)
+			if tc.wantErr != nil {
+				assert.True(t, errors.Is(err, tc.wantErr), err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, json.Number("18"), user.Age)
+		})
+	}
+}
@@ -31,7 +31,8 @@ func (b *MiddlewareBuilder) Build() web.Middleware {
 				al := accessLog{
 					Path:         ctx.Req.URL.Path,
 					HttpMethod:   ctx.Req.Method,
-					Host:         ctx.Req.Host,
+					Host:         ctx.Host(),
+					ClientIP:     ctx.ClientIP(),
 					MatchedRoute: ctx.MatchedRoute,
 				}
 
@@ -49,6 +50,7 @@ func (b *MiddlewareBuilder) Build() web.Middleware {
 
 type accessLog struct {
 	Host         string
+	ClientIP     string
 	HttpMethod   string
 	Path         string
 	MatchedRoute string
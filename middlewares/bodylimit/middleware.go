@@ -0,0 +1,23 @@
+package bodylimit
+
+import (
+	"github.com/uzziahlin/web"
+)
+
+// MiddlewareBuilder 限制单个路由的请求体大小，会覆盖 web.ServerWithBodyLimit 设置的全局限制
+// 超过限制时读取请求体返回 web.ErrBodyTooLarge，交给 Server 的错误处理逻辑时输出 413
+type MiddlewareBuilder struct {
+	// Limit 请求体的最大字节数，小于等于0时不设置路由级别的限制，此时 web.ServerWithBodyLimit 设置的全局限制依旧生效
+	Limit int64
+}
+
+func (b MiddlewareBuilder) Build() web.Middleware {
+	return func(next web.HandleFunc) web.HandleFunc {
+		return func(ctx *web.Context) {
+			if b.Limit > 0 {
+				ctx.LimitBody(b.Limit)
+			}
+			next(ctx)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package bodylimit
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uzziahlin/web"
+)
+
+func TestMiddlewareBuilder_Build(t *testing.T) {
+	handler := web.HandleErr(func(ctx *web.Context) error {
+		data, err := io.ReadAll(ctx.Req.Body)
+		if err != nil {
+			return err
+		}
+		return ctx.WriteString(http.StatusOK, string(data))
+	})
+
+	testCases := []struct {
+		name       string
+		opts       []web.Option
+		limit      int64
+		body       string
+		wantStatus int
+	}{
+		{name: "within limit", limit: 8, body: "12345678", wantStatus: http.StatusOK},
+		{name: "too large", limit: 8, body: "123456789", wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "override global", opts: []web.Option{web.ServerWithBodyLimit(4)}, limit: 16, body: "12345678", wantStatus: http.StatusOK},
+		{name: "zero keeps global", opts: []web.Option{web.ServerWithBodyLimit(4)}, body: "12345678", wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "zero without global", body: "12345678", wantStatus: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := web.NewHttpServer("", tc.opts...)
+			s.Post("/upload", handler)
+			s.Use(http.MethodPost, "/upload", MiddlewareBuilder{Limit: tc.limit}.Build())
+
+			resp := httptest.NewRecorder()
+			s.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(tc.body)))
+			assert.Equal(t, tc.wantStatus, resp.Code)
+		})
+	}
+}
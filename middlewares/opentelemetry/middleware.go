@@ -30,12 +30,12 @@ func (b *MiddlewareBuilder) Build() web.Middleware {
 			defer span.End()
 
 			span.SetAttributes(attribute.String("http.method", ctx.Req.Method))
-			span.SetAttributes(attribute.String("peer.hostname", ctx.Req.Host))
+			span.SetAttributes(attribute.String("peer.hostname", ctx.Host()))
 			span.SetAttributes(attribute.String("http.url", ctx.Req.URL.String()))
-			span.SetAttributes(attribute.String("http.scheme", ctx.Req.URL.Scheme))
+			span.SetAttributes(attribute.String("http.scheme", ctx.Scheme()))
 			span.SetAttributes(attribute.String("span.kind", "server"))
 			span.SetAttributes(attribute.String("component", "web"))
-			span.SetAttributes(attribute.String("peer.address", ctx.Req.RemoteAddr))
+			span.SetAttributes(attribute.String("peer.address", ctx.ClientIP()))
 			span.SetAttributes(attribute.String("http.proto", ctx.Req.Proto))
 
 			ctx.Req = ctx.Req.WithContext(reqCtx)
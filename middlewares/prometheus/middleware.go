@@ -27,17 +27,19 @@ func (m *MiddlewareBuilder) Build() web.Middleware {
 			startTime := time.Now()
 			next(ctx)
 			endTime := time.Now()
-			go report(endTime.Sub(startTime), ctx, summaryVec)
+
+			// Context 在请求结束后会被复用，因此需要在当前 goroutine 中取出需要上报的数据
+			route := "unknown"
+			if ctx.MatchedRoute != "" {
+				route = ctx.MatchedRoute
+			}
+
+			go report(endTime.Sub(startTime), route, ctx.Req.Method, ctx.RespStatus, summaryVec)
 		}
 	}
 }
 
-func report(dur time.Duration, ctx *web.Context, vec prometheus.ObserverVec) {
-	status := ctx.RespStatus
-	route := "unknown"
-	if ctx.MatchedRoute != "" {
-		route = ctx.MatchedRoute
-	}
+func report(dur time.Duration, route, method string, status int, vec prometheus.ObserverVec) {
 	ms := dur / time.Millisecond
-	vec.WithLabelValues(route, ctx.Req.Method, strconv.Itoa(status)).Observe(float64(ms))
+	vec.WithLabelValues(route, method, strconv.Itoa(status)).Observe(float64(ms))
 }
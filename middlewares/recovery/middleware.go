@@ -1,6 +1,11 @@
 package recovery
 
-import "github.com/uzziahlin/web"
+import (
+	"net/http"
+	"runtime/debug"
+
+	"github.com/uzziahlin/web"
+)
 
 type MiddlewareBuilder struct {
 	StatusCode int
@@ -9,13 +14,26 @@ type MiddlewareBuilder struct {
 }
 
 func (b MiddlewareBuilder) Build() web.Middleware {
+	status := b.StatusCode
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+
+	msg := b.ErrMsg
+	if msg == "" {
+		msg = http.StatusText(status)
+	}
+
 	return func(next web.HandleFunc) web.HandleFunc {
 		return func(ctx *web.Context) {
 			defer func() {
 				if err := recover(); err != nil {
-					ctx.RespStatus = b.StatusCode
-					ctx.RespData = []byte(b.ErrMsg)
-					b.LogFunc(ctx)
+					// 交给 Server 统一的错误处理逻辑输出响应
+					ctx.HandleError(web.NewHTTPError(status, "internal_error", msg).
+						WithError(&web.PanicError{Value: err, Stack: debug.Stack()}))
+					if b.LogFunc != nil {
+						b.LogFunc(ctx)
+					}
 				}
 			}()
 
@@ -0,0 +1,288 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// defaultMultipartMemory 和 net/http 的默认值保持一致
+const defaultMultipartMemory = 32 << 20
+
+// ErrFileTooLarge 上传的文件超过了大小限制
+var ErrFileTooLarge = NewHTTPError(http.StatusRequestEntityTooLarge, "file_too_large", "上传的文件过大")
+
+var (
+	fileHeaderType      = reflect.TypeOf((*multipart.FileHeader)(nil))
+	fileHeaderSliceType = reflect.TypeOf([]*multipart.FileHeader(nil))
+)
+
+// ServerWithMultipartMemory 设置解析 multipart 表单时保存在内存中的最大字节数，超过的部分写入临时文件
+func ServerWithMultipartMemory(n int64) Option {
+	return func(httpServer *DefaultHttpServer) {
+		httpServer.multipartMemory = n
+	}
+}
+
+// ServerWithMaxFileSize 设置 Bind 时单个文件的大小限制，字段的 max_size 标签可以覆盖该限制
+func ServerWithMaxFileSize(n int64) Option {
+	return func(httpServer *DefaultHttpServer) {
+		httpServer.maxFileSize = n
+	}
+}
+
+func (c *Context) multipartMemory() int64 {
+	if c.srv != nil && c.srv.multipartMemory > 0 {
+		return c.srv.multipartMemory
+	}
+	return defaultMultipartMemory
+}
+
+func (c *Context) maxFileSize() int64 {
+	if c.srv != nil {
+		return c.srv.maxFileSize
+	}
+	return 0
+}
+
+// isMultipart 请求体是否为 multipart/form-data
+func (c *Context) isMultipart() bool {
+	mediaType, _, _ := mime.ParseMediaType(c.Req.Header.Get("Content-Type"))
+	return mediaType == MIMEMultipart
+}
+
+// parseMultipart 解析 multipart 表单，解析之后普通字段可以通过 Req.Form 获取
+func (c *Context) parseMultipart() error {
+	if c.Req.MultipartForm != nil {
+		return nil
+	}
+	if err := c.Req.ParseMultipartForm(c.multipartMemory()); err != nil {
+		return decodeError(err)
+	}
+	return nil
+}
+
+// parseMultipartLimit 解析 multipart 表单，读取的同时检查文件的大小，超过 limits 中对应字段的限制时立刻停止读取
+// 超过限制时返回对应字段的 *BindError，表单已经解析过时不再检查
+// 没有任何限制时直接使用 parseMultipart，避免重新编码带来的额外拷贝
+func (c *Context) parseMultipartLimit(limits map[string]bindField) error {
+	if c.Req.MultipartForm != nil {
+		return nil
+	}
+
+	if c.maxFileSize() <= 0 && !hasMaxSize(limits) {
+		return c.parseMultipart()
+	}
+
+	reader, err := c.Req.MultipartReader()
+	if err != nil {
+		return decodeError(err)
+	}
+
+	// 逐个读取并检查之后重新编码，交给 multipart.Reader.ReadForm 生成 *multipart.FileHeader
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+
+	type result struct {
+		form *multipart.Form
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		form, err := multipart.NewReader(pr, writer.Boundary()).ReadForm(c.multipartMemory())
+		// ReadForm 提前返回时让写入的一方停止
+		_ = pr.CloseWithError(err)
+		done <- result{form, err}
+	}()
+
+	copyErr := c.copyParts(reader, writer, limits)
+	_ = pw.CloseWithError(copyErr)
+	res := <-done
+
+	var (
+		he *HTTPError
+		be *BindError
+	)
+	switch {
+	case copyErr != nil && (errors.As(copyErr, &he) || errors.As(copyErr, &be)):
+		if res.form != nil {
+			_ = res.form.RemoveAll()
+		}
+		return copyErr
+	case res.err != nil:
+		return decodeError(res.err)
+	case copyErr != nil:
+		return copyErr
+	}
+
+	// 和 ParseMultipartForm 一样，普通字段同时放到 Form 和 PostForm 中
+	if err = c.Req.ParseForm(); err != nil {
+		_ = res.form.RemoveAll()
+		return decodeError(err)
+	}
+	if c.Req.PostForm == nil {
+		c.Req.PostForm = url.Values{}
+	}
+	for k, v := range res.form.Value {
+		c.Req.Form[k] = append(c.Req.Form[k], v...)
+		c.Req.PostForm[k] = append(c.Req.PostForm[k], v...)
+	}
+	c.Req.MultipartForm = res.form
+
+	return nil
+}
+
+func hasMaxSize(limits map[string]bindField) bool {
+	for _, f := range limits {
+		if f.maxSize > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Context) copyParts(reader *multipart.Reader, writer *multipart.Writer, limits map[string]bindField) error {
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return writer.Close()
+		}
+		if err != nil {
+			return decodeError(err)
+		}
+
+		dst, err := writer.CreatePart(part.Header)
+		if err != nil {
+			return err
+		}
+
+		var src io.Reader = bodyReader{part}
+
+		f, ok := limits[part.FormName()]
+		limit := f.maxSize
+		if limit == 0 {
+			limit = c.maxFileSize()
+		}
+		if !ok || part.FileName() == "" || limit <= 0 {
+			if _, err = io.Copy(dst, src); err != nil {
+				return err
+			}
+			continue
+		}
+
+		n, err := io.Copy(dst, io.LimitReader(src, limit+1))
+		if err != nil {
+			return err
+		}
+		if n > limit {
+			return &BindError{
+				Field:  f.path,
+				Source: "form",
+				Name:   part.FormName(),
+				Value:  part.FileName(),
+				Err:    ErrFileTooLarge,
+			}
+		}
+	}
+}
+
+// EachPart 按照到达的顺序逐个处理 multipart 表单中的部分，数据不会先缓存到内存或者临时文件
+// fn 返回之后 part 会被关闭，未读取的数据会被丢弃，fn 返回错误时停止处理并返回该错误
+// 不能和 Bind、GetForm、Req.FormFile 等需要解析整个表单的方法一起使用
+func (c *Context) EachPart(fn func(part *multipart.Part) error) error {
+	reader, err := c.Req.MultipartReader()
+	if err != nil {
+		if errors.Is(err, http.ErrNotMultipart) {
+			return errUnsupportedMediaType.WithError(err)
+		}
+		return decodeError(err)
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return decodeError(err)
+		}
+
+		err = fn(part)
+		_ = part.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// bindFile 绑定 *multipart.FileHeader 和 []*multipart.FileHeader 类型的字段
+func (c *Context) bindFile(field reflect.Value, f bindField) *BindError {
+	name := f.names["form"]
+
+	var headers []*multipart.FileHeader
+	if c.Req.MultipartForm != nil {
+		headers = c.Req.MultipartForm.File[name]
+	}
+	if len(headers) == 0 {
+		return nil
+	}
+
+	limit := f.maxSize
+	if limit == 0 {
+		limit = c.maxFileSize()
+	}
+
+	if limit > 0 {
+		for _, h := range headers {
+			if h.Size > limit {
+				return &BindError{
+					Field:  f.path,
+					Source: "form",
+					Name:   name,
+					Value:  h.Filename,
+					Err:    ErrFileTooLarge,
+				}
+			}
+		}
+	}
+
+	if field.Type() == fileHeaderType {
+		field.Set(reflect.ValueOf(headers[0]))
+	} else {
+		field.Set(reflect.ValueOf(headers))
+	}
+	return nil
+}
+
+func isFileType(typ reflect.Type) bool {
+	return typ == fileHeaderType || typ == fileHeaderSliceType
+}
+
+// parseSize 解析 max_size 标签，支持不带单位的字节数以及 KB、MB、GB，例如 512KB、10MB
+func parseSize(tag string) (int64, error) {
+	size := strings.ToUpper(strings.TrimSpace(tag))
+
+	unit := int64(1)
+	for _, u := range []struct {
+		suffix string
+		unit   int64
+	}{{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"B", 1}} {
+		if strings.HasSuffix(size, u.suffix) {
+			size, unit = strings.TrimSuffix(size, u.suffix), u.unit
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(strings.TrimSpace(size), 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("web: 不合法的文件大小 %q", tag)
+	}
+	return n * unit, nil
+}
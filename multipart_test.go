@@ -0,0 +1,269 @@
+package web
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type uploadForm struct {
+	Title       string                  `form:"title" validate:"required"`
+	Avatar      *multipart.FileHeader   `form:"avatar" max_size:"1KB"`
+	Attachments []*multipart.FileHeader `form:"attachment"`
+}
+
+func newMultipartRequest(t *testing.T, fields map[string]string, files map[string][]string) *http.Request {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for k, v := range fields {
+		require.NoError(t, w.WriteField(k, v))
+	}
+	for field, contents := range files {
+		for i, content := range contents {
+			fw, err := w.CreateFormFile(field, field+string(rune('a'+i))+".txt")
+			require.NoError(t, err)
+			_, err = fw.Write([]byte(content))
+			require.NoError(t, err)
+		}
+	}
+	require.NoError(t, w.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req
+}
+
+func TestContext_BindMultipart(t *testing.T) {
+	req := newMultipartRequest(t,
+		map[string]string{"title": "报告"},
+		map[string][]string{"avatar": {"png"}, "attachment": {"one", "two"}},
+	)
+	ctx := NewContext(httptest.NewRecorder(), req)
+
+	var form uploadForm
+	require.NoError(t, ctx.Bind(&form))
+
+	assert.Equal(t, "报告", form.Title)
+	require.NotNil(t, form.Avatar)
+	assert.Equal(t, "avatara.txt", form.Avatar.Filename)
+	require.Len(t, form.Attachments, 2)
+	assert.Equal(t, int64(3), form.Attachments[1].Size)
+
+	// max_size 标签
+	req = newMultipartRequest(t,
+		map[string]string{"title": "报告"},
+		map[string][]string{"avatar": {strings.Repeat("x", 2048)}},
+	)
+	ctx = NewContext(httptest.NewRecorder(), req)
+	err := ctx.Bind(&uploadForm{})
+	assert.True(t, errors.Is(err, ErrFileTooLarge))
+
+	// Server 设置的限制
+	req = newMultipartRequest(t,
+		map[string]string{"title": "报告"},
+		map[string][]string{"attachment": {"one", "too large"}},
+	)
+	ctx = NewContext(httptest.NewRecorder(), req)
+	ctx.srv = NewHttpServer("", ServerWithMaxFileSize(4), ServerWithMultipartMemory(1)).(*DefaultHttpServer)
+	err = ctx.Bind(&uploadForm{})
+	assert.True(t, errors.Is(err, ErrFileTooLarge))
+
+	var errs BindErrors
+	require.True(t, errors.As(err, &errs))
+	assert.Equal(t, "Attachments", errs[0].Field)
+
+	// 标签不合法时返回错误
+	err = ctx.Bind(&struct {
+		File *multipart.FileHeader `form:"file" max_size:"big"`
+	}{})
+	assert.ErrorContains(t, err, "max_size 标签不合法")
+}
+
+func TestContext_BindMultipartWithoutLimit(t *testing.T) {
+	req := newMultipartRequest(t,
+		map[string]string{"title": "报告"},
+		map[string][]string{"attachment": {"one", strings.Repeat("x", 2048)}},
+	)
+	ctx := NewContext(httptest.NewRecorder(), req)
+
+	// 没有 max_size 标签和 Server 设置的限制时不检查文件大小
+	var form struct {
+		Title       string                  `form:"title"`
+		Attachments []*multipart.FileHeader `form:"attachment"`
+	}
+	require.NoError(t, ctx.Bind(&form))
+
+	assert.Equal(t, "报告", form.Title)
+	require.Len(t, form.Attachments, 2)
+	assert.Equal(t, int64(2048), form.Attachments[1].Size)
+	assert.Equal(t, []string{"报告"}, ctx.Req.PostForm["title"])
+}
+
+type countingReader struct {
+	io.Reader
+	n int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += n
+	return n, err
+}
+
+func TestContext_BindMultipartStreamingLimit(t *testing.T) {
+	req := newMultipartRequest(t,
+		map[string]string{"title": "报告"},
+		map[string][]string{"avatar": {strings.Repeat("x", 1<<20)}},
+	)
+	body := &countingReader{Reader: req.Body}
+	req.Body = io.NopCloser(body)
+
+	ctx := NewContext(httptest.NewRecorder(), req)
+	err := ctx.Bind(&uploadForm{})
+	assert.True(t, errors.Is(err, ErrFileTooLarge))
+
+	var errs BindErrors
+	require.True(t, errors.As(err, &errs))
+	assert.Equal(t, "Avatar", errs[0].Field)
+
+	// 超过限制时立刻停止读取，不会先缓存整个文件
+	assert.Less(t, body.n, 64<<10)
+}
+
+func TestFileUploader_Streaming(t *testing.T) {
+	dir := t.TempDir()
+
+	var uploaded []UploadedFile
+	uploader := &FileUploader{
+		FileField: "file",
+		Streaming: true,
+		PathFunc: func(h *multipart.FileHeader) string {
+			return filepath.Join(dir, h.Filename)
+		},
+		OnUploaded: func(ctx *Context, file UploadedFile) error {
+			uploaded = append(uploaded, file)
+			return nil
+		},
+	}
+
+	s := NewHttpServer("", ServerWithBodyLimit(1<<10))
+	s.Post("/upload", uploader.Handle())
+
+	req := newMultipartRequest(t, map[string]string{"title": "报告"}, map[string][]string{"file": {"hello", "world!"}})
+	resp := httptest.NewRecorder()
+	s.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	require.Len(t, uploaded, 2)
+	sum := sha256.Sum256([]byte("world!"))
+	assert.Equal(t, UploadedFile{
+		Field:    "file",
+		Filename: "fileb.txt",
+		Path:     filepath.Join(dir, "fileb.txt"),
+		Size:     6,
+		Hash:     sum[:],
+	}, uploaded[1])
+
+	data, err := os.ReadFile(filepath.Join(dir, "filea.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+
+	// 超过请求体大小限制时不保留不完整的文件
+	req = newMultipartRequest(t, nil, map[string][]string{"file": {strings.Repeat("x", 2<<10)}})
+	resp = httptest.NewRecorder()
+	s.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.Code)
+	_, err = os.Stat(filepath.Join(dir, "filea.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	// 没有文件
+	req = newMultipartRequest(t, map[string]string{"title": "报告"}, nil)
+	resp = httptest.NewRecorder()
+	s.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	// 请求体不是合法的 multipart 表单
+	req = httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("--x\r\nbroken"))
+	req.Header.Set("Content-Type", "multipart/form-data; boundary=x")
+	resp = httptest.NewRecorder()
+	s.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	// 写入文件失败属于服务端错误
+	uploader.PathFunc = func(h *multipart.FileHeader) string {
+		return filepath.Join(dir, "missing", h.Filename)
+	}
+	req = newMultipartRequest(t, nil, map[string][]string{"file": {"hello"}})
+	resp = httptest.NewRecorder()
+	s.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+}
+
+func TestFileUploader_HashOnlyWithCallback(t *testing.T) {
+	dir := t.TempDir()
+
+	hashed := 0
+	uploader := &FileUploader{
+		FileField: "file",
+		PathFunc: func(h *multipart.FileHeader) string {
+			return filepath.Join(dir, h.Filename)
+		},
+		Hash: func() hash.Hash {
+			hashed++
+			return sha256.New()
+		},
+	}
+
+	s := NewHttpServer("")
+	s.Post("/upload", uploader.Handle())
+
+	// 没有 OnUploaded 时不计算摘要
+	req := newMultipartRequest(t, nil, map[string][]string{"file": {"hello"}})
+	resp := httptest.NewRecorder()
+	s.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, 0, hashed)
+
+	var uploaded UploadedFile
+	uploader.OnUploaded = func(ctx *Context, file UploadedFile) error {
+		uploaded = file
+		return nil
+	}
+	req = newMultipartRequest(t, nil, map[string][]string{"file": {"hello"}})
+	resp = httptest.NewRecorder()
+	s.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, 1, hashed)
+	sum := sha256.Sum256([]byte("hello"))
+	assert.Equal(t, sum[:], uploaded.Hash)
+}
+
+func TestFileUploader_MissingFile(t *testing.T) {
+	uploader := &FileUploader{FileField: "file"}
+
+	s := NewHttpServer("")
+	s.Post("/upload", uploader.Handle())
+
+	req := newMultipartRequest(t, map[string]string{"title": "报告"}, nil)
+	resp := httptest.NewRecorder()
+	s.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("title=x"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp = httptest.NewRecorder()
+	s.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
@@ -0,0 +1,143 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// ProblemContentType RFC 7807 规定的响应类型
+const ProblemContentType = "application/problem+json"
+
+// Problem RFC 7807 problem details，用于输出机器可读的错误信息
+// Problem 本身也实现了 error，可以直接在 ErrHandleFunc 中返回
+type Problem struct {
+	// Type 标识错误类型的 URI，为空时按照 about:blank 处理
+	Type string `json:"type,omitempty"`
+	// Title 错误类型的简短描述
+	Title string `json:"title,omitempty"`
+	// Status http 响应码
+	Status int `json:"status,omitempty"`
+	// Detail 本次错误的具体描述
+	Detail string `json:"detail,omitempty"`
+	// Instance 标识本次错误的 URI，一般为请求路径
+	Instance string `json:"instance,omitempty"`
+	// Extensions 扩展字段，与标准字段平铺输出，不能覆盖标准字段
+	Extensions map[string]any `json:"-"`
+}
+
+// NewProblem 创建一个 about:blank 类型的 Problem，Title 为响应码对应的描述
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+func (p *Problem) Error() string {
+	return "web: " + p.Title + ": " + p.Detail
+}
+
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	// 使用别名避免递归调用 MarshalJSON
+	type problem Problem
+
+	data, err := json.Marshal((*problem)(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return data, err
+	}
+
+	res := make(map[string]any, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		res[k] = v
+	}
+
+	var std map[string]any
+	if err = json.Unmarshal(data, &std); err != nil {
+		return nil, err
+	}
+	for k, v := range std {
+		res[k] = v
+	}
+
+	return json.Marshal(res)
+}
+
+// WriteProblem 以 application/problem+json 格式输出 Problem
+func (c *Context) WriteProblem(p *Problem) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	status := p.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+
+	c.Resp.Header().Set("Content-Type", ProblemContentType)
+	c.RespStatus = status
+	c.RespData = data
+
+	return nil
+}
+
+// ServerWithProblemDetails 使用 ProblemErrorHandler 处理错误
+// 路由未命中、内置的文件处理逻辑以及 recovery 中间件的错误都会以 problem details 输出
+func ServerWithProblemDetails() Option {
+	return ServerWithErrorHandler(ProblemErrorHandler)
+}
+
+// ProblemErrorHandler 以 problem details 格式输出错误
+// HTTPError 转换为 about:blank 类型的 Problem，错误码放在扩展字段 code 中
+// 其余错误一律按照 500 处理，避免泄露内部错误
+func ProblemErrorHandler(ctx *Context, err error) {
+	var p *Problem
+	if !errors.As(err, &p) {
+		p = problemOf(ctx, err)
+		p.Instance = ctx.Req.URL.Path
+	}
+
+	if err = ctx.WriteProblem(p); err != nil {
+		DefaultErrorHandler(ctx, err)
+	}
+}
+
+func problemOf(ctx *Context, err error) *Problem {
+	var he *HTTPError
+	if !errors.As(err, &he) {
+		return NewProblem(http.StatusInternalServerError, "")
+	}
+
+	p := NewProblem(he.Status, ctx.errorMessage(he))
+	p.Extensions = map[string]any{}
+
+	if he.Code != "" {
+		p.Extensions["code"] = he.Code
+	}
+
+	// 绑定和校验失败时，列出具体的字段，便于客户端展示
+	var (
+		bindErrs     BindErrors
+		validateErrs ValidationErrors
+	)
+
+	switch {
+	case errors.As(err, &bindErrs):
+		fields := make([]map[string]string, 0, len(bindErrs))
+		for _, e := range bindErrs {
+			fields = append(fields, map[string]string{"field": e.Field, "source": e.Source, "name": e.Name})
+		}
+		p.Extensions["errors"] = fields
+	case errors.As(err, &validateErrs):
+		fields := make([]map[string]string, 0, len(validateErrs))
+		for _, e := range validateErrs {
+			fields = append(fields, map[string]string{"field": e.Field, "rule": e.Rule, "param": e.Param})
+		}
+		p.Extensions["errors"] = fields
+	}
+
+	return p
+}
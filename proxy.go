@@ -0,0 +1,212 @@
+package web
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/uzziahlin/web/proxyproto"
+)
+
+// ServerWithTrustedProxies 设置可信的代理，例如负载均衡所在的网段，支持 CIDR 和单个 IP
+// 只有请求来自可信的代理时，ClientIP、Scheme 和 Host 才会使用 Forwarded、X-Forwarded-* 以及 X-Real-IP
+// 不合法的地址会 panic
+func ServerWithTrustedProxies(cidrs ...string) Option {
+	nets, err := proxyproto.ParseCIDRs(cidrs...)
+	if err != nil {
+		panic(fmt.Sprintf("web: 不合法的代理地址: %v", err))
+	}
+
+	return func(httpServer *DefaultHttpServer) {
+		httpServer.trustedProxies = nets
+	}
+}
+
+// isTrustedProxy 判断 ip 是否属于可信的代理，没有设置可信的代理时都不可信
+func (c *Context) isTrustedProxy(ip net.IP) bool {
+	if c.srv == nil || ip == nil {
+		return false
+	}
+	for _, ipNet := range c.srv.trustedProxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteIP 返回直接连接的对端地址
+func (c *Context) remoteIP() string {
+	host, _, err := net.SplitHostPort(c.Req.RemoteAddr)
+	if err != nil {
+		return c.Req.RemoteAddr
+	}
+	return host
+}
+
+// fromTrustedProxy 请求是否由可信的代理转发
+func (c *Context) fromTrustedProxy() bool {
+	return c.isTrustedProxy(net.ParseIP(c.remoteIP()))
+}
+
+// ClientIP 返回客户端的 IP
+// 请求来自可信的代理时，依次使用 Forwarded、X-Forwarded-For 和 X-Real-IP，
+// 从右往左跳过可信的代理，第一个不可信的地址即为客户端的地址，该地址不是 IP 时返回直接连接的对端地址
+func (c *Context) ClientIP() string {
+	remote := c.remoteIP()
+	if !c.isTrustedProxy(net.ParseIP(remote)) {
+		return remote
+	}
+
+	chain := c.forwardedFor()
+	if len(chain) == 0 {
+		if ip := strings.TrimSpace(c.Req.Header.Get("X-Real-IP")); net.ParseIP(ip) != nil {
+			return ip
+		}
+		return remote
+	}
+
+	// 第一个不可信的地址不是 IP，例如 unknown，无法确定客户端的地址时使用直接连接的对端地址
+	if ip := chain[c.clientHop(chain)]; ip != "" {
+		return ip
+	}
+	return remote
+}
+
+// clientHop 从右往左跳过可信的代理，返回第一个不可信的地址的下标
+// 该地址由离客户端最近的可信代理添加，所有的地址都是可信的代理时返回0
+func (c *Context) clientHop(chain []string) int {
+	for i := len(chain) - 1; i > 0; i-- {
+		if !c.isTrustedProxy(net.ParseIP(chain[i])) {
+			return i
+		}
+	}
+	return 0
+}
+
+// Scheme 返回客户端使用的协议，http 或者 https
+// 请求来自可信的代理时，依次使用 Forwarded 的 proto 和 X-Forwarded-Proto
+// 和 ClientIP 一样只使用可信的代理添加的值，客户端自己伪造的值会被忽略
+func (c *Context) Scheme() string {
+	if c.fromTrustedProxy() {
+		if proto := c.forwardedParam("proto"); proto != "" {
+			return strings.ToLower(proto)
+		}
+		if proto := c.forwardedHeader("X-Forwarded-Proto"); proto != "" {
+			return strings.ToLower(proto)
+		}
+	}
+
+	if c.Req.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// Host 返回客户端请求的主机名
+// 请求来自可信的代理时，依次使用 Forwarded 的 host 和 X-Forwarded-Host，取值的规则和 Scheme 相同
+func (c *Context) Host() string {
+	if c.fromTrustedProxy() {
+		if host := c.forwardedParam("host"); host != "" {
+			return host
+		}
+		if host := c.forwardedHeader("X-Forwarded-Host"); host != "" {
+			return host
+		}
+	}
+	return c.Req.Host
+}
+
+// forwardedFor 返回 Forwarded 中的 for，没有 Forwarded 时返回 X-Forwarded-For
+// unknown、混淆过的名称以及不合法的地址保留为空字符串，保证每个元素的位置和代理的跳数一致
+func (c *Context) forwardedFor() []string {
+	if elems := parseForwarded(c.Req.Header.Values("Forwarded")); len(elems) > 0 {
+		res := make([]string, len(elems))
+		for i, elem := range elems {
+			res[i] = forwardedIP(elem["for"])
+		}
+		return res
+	}
+
+	res := headerValues(c.Req.Header.Values("X-Forwarded-For"))
+	for i, val := range res {
+		if net.ParseIP(val) == nil {
+			res[i] = ""
+		}
+	}
+	return res
+}
+
+// forwardedParam 返回 Forwarded 中离客户端最近的可信代理添加的元素的参数
+// 和 ClientIP 一样从右往左跳过 for 为可信代理的元素
+func (c *Context) forwardedParam(name string) string {
+	elems := parseForwarded(c.Req.Header.Values("Forwarded"))
+	if len(elems) == 0 {
+		return ""
+	}
+
+	return elems[c.clientHop(c.forwardedFor())][name]
+}
+
+// forwardedHeader 返回 X-Forwarded-Proto、X-Forwarded-Host 中可信代理添加的值
+// 值的个数和 X-Forwarded-For 一致时，使用 ClientIP 对应的位置，否则使用最右边的值，即直接连接的代理添加的值
+func (c *Context) forwardedHeader(name string) string {
+	vals := headerValues(c.Req.Header.Values(name))
+	if len(vals) == 0 {
+		return ""
+	}
+
+	chain := headerValues(c.Req.Header.Values("X-Forwarded-For"))
+	if len(chain) == len(vals) {
+		return vals[c.clientHop(chain)]
+	}
+	return vals[len(vals)-1]
+}
+
+// parseForwarded 解析 RFC 7239 的 Forwarded 请求头，例如
+//
+//	Forwarded: for=192.0.2.60;proto=https;host=example.com, for="[2001:db8::1]:4711"
+func parseForwarded(lines []string) []map[string]string {
+	var res []map[string]string
+
+	for _, line := range lines {
+		for _, elem := range strings.Split(line, ",") {
+			params := map[string]string{}
+			for _, pair := range strings.Split(elem, ";") {
+				key, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok {
+					continue
+				}
+				params[strings.ToLower(key)] = strings.Trim(val, `"`)
+			}
+			if len(params) > 0 {
+				res = append(res, params)
+			}
+		}
+	}
+
+	return res
+}
+
+// forwardedIP 去掉 Forwarded 中地址的端口和方括号，unknown 以及混淆过的名称返回空字符串
+func forwardedIP(node string) string {
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		node = host
+	}
+	node = strings.TrimSuffix(strings.TrimPrefix(node, "["), "]")
+	if net.ParseIP(node) == nil {
+		return ""
+	}
+	return node
+}
+
+// headerValues 拆分逗号分隔的请求头
+func headerValues(lines []string) []string {
+	var res []string
+	for _, line := range lines {
+		for _, val := range strings.Split(line, ",") {
+			res = append(res, strings.TrimSpace(val))
+		}
+	}
+	return res
+}
@@ -0,0 +1,140 @@
+package web
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContext_ClientIP(t *testing.T) {
+	srv := NewHttpServer("", ServerWithTrustedProxies("10.0.0.0/8", "192.168.1.1")).(*DefaultHttpServer)
+
+	testCases := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		tls        bool
+
+		wantIP     string
+		wantScheme string
+		wantHost   string
+	}{
+		{
+			name:       "untrusted remote",
+			remoteAddr: "203.0.113.9:1234",
+			headers:    map[string]string{"X-Forwarded-For": "1.1.1.1", "X-Forwarded-Proto": "https", "X-Forwarded-Host": "evil.com"},
+			wantIP:     "203.0.113.9",
+			wantScheme: "http",
+			wantHost:   "example.com",
+		},
+		{
+			name:       "x-forwarded",
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string]string{"X-Forwarded-For": "6.6.6.6, 198.51.100.7, 192.168.1.1", "X-Forwarded-Proto": "https, http", "X-Forwarded-Host": "evil.com, shop.example.com"},
+			wantIP:     "198.51.100.7",
+			wantScheme: "http",
+			wantHost:   "shop.example.com",
+		},
+		{
+			name:       "x-forwarded spoofed",
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string]string{"X-Forwarded-For": "6.6.6.6, 198.51.100.7, 192.168.1.1", "X-Forwarded-Proto": "http, https, http", "X-Forwarded-Host": "evil.com, shop.example.com, internal"},
+			wantIP:     "198.51.100.7",
+			wantScheme: "https",
+			wantHost:   "shop.example.com",
+		},
+		{
+			name:       "forwarded",
+			remoteAddr: "10.0.0.2:1234",
+			headers: map[string]string{
+				"Forwarded":       `for="[2001:db8::1]:4711";proto=https;host=api.example.com, for=10.1.1.1`,
+				"X-Forwarded-For": "1.1.1.1",
+			},
+			wantIP:     "2001:db8::1",
+			wantScheme: "https",
+			wantHost:   "api.example.com",
+		},
+		{
+			name:       "forwarded spoofed",
+			remoteAddr: "10.0.0.2:1234",
+			headers: map[string]string{
+				"Forwarded": `for=6.6.6.6;proto=http;host=evil.com, for=198.51.100.7;proto=https;host=api.example.com, for=10.1.1.1`,
+			},
+			wantIP:     "198.51.100.7",
+			wantScheme: "https",
+			wantHost:   "api.example.com",
+		},
+		{
+			name:       "forwarded unknown",
+			remoteAddr: "10.0.0.2:1234",
+			headers: map[string]string{
+				"Forwarded": `for=6.6.6.6;proto=http;host=evil.com, for=unknown;proto=https;host=api.example.com`,
+			},
+			wantIP:     "10.0.0.2",
+			wantScheme: "https",
+			wantHost:   "api.example.com",
+		},
+		{
+			name:       "forwarded obfuscated",
+			remoteAddr: "10.0.0.2:1234",
+			headers: map[string]string{
+				"Forwarded": `for=6.6.6.6, for="_hidden:_port", for=10.1.1.1`,
+			},
+			wantIP:     "10.0.0.2",
+			wantScheme: "http",
+			wantHost:   "example.com",
+		},
+		{
+			name:       "x-forwarded invalid",
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string]string{"X-Forwarded-For": "6.6.6.6, unknown"},
+			wantIP:     "10.0.0.2",
+			wantScheme: "http",
+			wantHost:   "example.com",
+		},
+		{
+			name:       "real ip",
+			remoteAddr: "192.168.1.1:1234",
+			headers:    map[string]string{"X-Real-IP": "198.51.100.8"},
+			tls:        true,
+			wantIP:     "198.51.100.8",
+			wantScheme: "https",
+			wantHost:   "example.com",
+		},
+		{
+			name:       "all trusted",
+			remoteAddr: "10.0.0.2:1234",
+			headers:    map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.4"},
+			wantIP:     "10.0.0.3",
+			wantScheme: "http",
+			wantHost:   "example.com",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+			req.RemoteAddr = tc.remoteAddr
+			if !tc.tls {
+				req.TLS = nil
+			} else {
+				req.TLS = &tls.ConnectionState{}
+			}
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+
+			ctx := NewContext(httptest.NewRecorder(), req)
+			ctx.srv = srv
+
+			assert.Equal(t, tc.wantIP, ctx.ClientIP())
+			assert.Equal(t, tc.wantScheme, ctx.Scheme())
+			assert.Equal(t, tc.wantHost, ctx.Host())
+		})
+	}
+
+	assert.Panics(t, func() { ServerWithTrustedProxies("not an ip") })
+}
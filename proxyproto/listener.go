@@ -0,0 +1,347 @@
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// v2Signature PROXY protocol v2 的固定签名
+	v2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+	errMissingHeader = errors.New("proxyproto: 缺少 PROXY protocol 头部")
+	errInvalidHeader = errors.New("proxyproto: PROXY protocol 头部不合法")
+)
+
+const (
+	// v1MaxLength v1 头部的最大长度，包括结尾的 \r\n
+	v1MaxLength = 107
+	// v2HeaderLength v2 固定头部长度
+	v2HeaderLength = 16
+)
+
+type Option func(l *Listener)
+
+// Listener 解析 PROXY protocol v1/v2 头部的监听套接字
+// 只有来自可信地址的连接才会解析头部，其余连接原样返回
+// 没有配置可信地址时不信任任何来源，需要信任所有来源时可以配置 0.0.0.0/0 和 ::/0
+type Listener struct {
+	net.Listener
+
+	trusted []*net.IPNet
+
+	headerTimeout time.Duration
+}
+
+func NewListener(l net.Listener, opts ...Option) *Listener {
+	res := &Listener{
+		Listener:      l,
+		headerTimeout: time.Second * 5,
+	}
+
+	for _, opt := range opts {
+		opt(res)
+	}
+
+	return res
+}
+
+// Wrap 返回包装函数，可以直接传给 web.ServerWithListenerWrapper
+func Wrap(opts ...Option) func(l net.Listener) net.Listener {
+	return func(l net.Listener) net.Listener {
+		return NewListener(l, opts...)
+	}
+}
+
+// WithTrustedCIDRs 设置可信的来源地址，例如负载均衡所在的网段，支持 CIDR 和单个 IP
+// 不合法的地址会 panic
+func WithTrustedCIDRs(cidrs ...string) Option {
+	nets, err := ParseCIDRs(cidrs...)
+	if err != nil {
+		panic(err.Error())
+	}
+	return func(l *Listener) {
+		l.trusted = append(l.trusted, nets...)
+	}
+}
+
+// ParseCIDRs 解析 CIDR 列表，单个 IP 会被当作只包含该地址的网段
+func ParseCIDRs(cidrs ...string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, fmt.Errorf("proxyproto: 不合法的 CIDR %s", cidr)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("proxyproto: 不合法的 CIDR %s", cidr)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// WithHeaderTimeout 设置读取头部的超时时间
+func WithHeaderTimeout(timeout time.Duration) Option {
+	return func(l *Listener) {
+		l.headerTimeout = timeout
+	}
+}
+
+func (l *Listener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	if !l.isTrusted(c.RemoteAddr()) {
+		return c, nil
+	}
+
+	return newConn(c, l.headerTimeout), nil
+}
+
+func (l *Listener) isTrusted(addr net.Addr) bool {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+
+	for _, ipNet := range l.trusted {
+		if ipNet.Contains(tcpAddr.IP) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Conn 解析了 PROXY protocol 头部的连接
+// 头部在第一次读取数据或者获取地址的时候才解析，避免阻塞 Accept
+type Conn struct {
+	net.Conn
+
+	reader *bufio.Reader
+
+	once sync.Once
+	err  error
+
+	timeout time.Duration
+
+	remoteAddr net.Addr
+	localAddr  net.Addr
+}
+
+func newConn(c net.Conn, timeout time.Duration) *Conn {
+	return &Conn{
+		Conn:    c,
+		reader:  bufio.NewReader(c),
+		timeout: timeout,
+	}
+}
+
+func (c *Conn) Read(b []byte) (int, error) {
+	c.once.Do(c.readHeader)
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.reader.Read(b)
+}
+
+// RemoteAddr 返回头部中记录的客户端地址，没有记录则返回连接本身的地址
+func (c *Conn) RemoteAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	if c.remoteAddr != nil {
+		return c.remoteAddr
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// LocalAddr 返回头部中记录的目标地址，没有记录则返回连接本身的地址
+func (c *Conn) LocalAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	if c.localAddr != nil {
+		return c.localAddr
+	}
+	return c.Conn.LocalAddr()
+}
+
+func (c *Conn) readHeader() {
+	if c.timeout > 0 {
+		_ = c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+		defer func() {
+			_ = c.Conn.SetReadDeadline(time.Time{})
+		}()
+	}
+
+	// v1 头部最短也超过 v2 签名的长度，因此可以先按照 v2 签名长度判断
+	sig, err := c.reader.Peek(len(v2Signature))
+	if err != nil {
+		c.err = fmt.Errorf("%w: %v", errMissingHeader, err)
+		return
+	}
+
+	switch {
+	case bytes.Equal(sig, v2Signature):
+		c.err = c.readV2()
+	case bytes.HasPrefix(sig, []byte("PROXY ")):
+		c.err = c.readV1()
+	default:
+		c.err = errMissingHeader
+	}
+}
+
+// readV1 解析文本格式的头部，例如
+// PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\n
+func (c *Conn) readV1() error {
+	line, err := c.reader.ReadSlice('\n')
+	if err != nil {
+		return fmt.Errorf("%w: %v", errInvalidHeader, err)
+	}
+
+	if len(line) > v1MaxLength || !bytes.HasSuffix(line, []byte("\r\n")) {
+		return errInvalidHeader
+	}
+
+	fields := strings.Split(string(line[:len(line)-2]), " ")
+
+	if len(fields) < 2 {
+		return errInvalidHeader
+	}
+
+	// 未知协议，忽略剩余内容，保留连接本身的地址
+	if fields[1] == "UNKNOWN" {
+		return nil
+	}
+
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return errInvalidHeader
+	}
+
+	src, err := parseV1Addr(fields[1], fields[2], fields[4])
+	if err != nil {
+		return err
+	}
+
+	dst, err := parseV1Addr(fields[1], fields[3], fields[5])
+	if err != nil {
+		return err
+	}
+
+	c.remoteAddr, c.localAddr = src, dst
+
+	return nil
+}
+
+func parseV1Addr(proto, ip, port string) (*net.TCPAddr, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return nil, errInvalidHeader
+	}
+
+	if (proto == "TCP4") != (addr.To4() != nil) {
+		return nil, errInvalidHeader
+	}
+
+	// 端口不允许有前导0
+	if len(port) > 1 && port[0] == '0' {
+		return nil, errInvalidHeader
+	}
+
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return nil, errInvalidHeader
+	}
+
+	return &net.TCPAddr{IP: addr, Port: int(p)}, nil
+}
+
+// readV2 解析二进制格式的头部
+func (c *Conn) readV2() error {
+	header := make([]byte, v2HeaderLength)
+	if _, err := io.ReadFull(c.reader, header); err != nil {
+		return fmt.Errorf("%w: %v", errInvalidHeader, err)
+	}
+
+	// 高4位为版本号，必须为2；低4位为命令，0 表示 LOCAL，1 表示 PROXY
+	if header[12]>>4 != 2 {
+		return errInvalidHeader
+	}
+	cmd := header[12] & 0x0f
+	if cmd > 1 {
+		return errInvalidHeader
+	}
+
+	// 高4位为协议族，低4位为传输协议，1 表示 STREAM
+	family := header[13] >> 4
+	transport := header[13] & 0x0f
+
+	length := binary.BigEndian.Uint16(header[14:16])
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return fmt.Errorf("%w: %v", errInvalidHeader, err)
+	}
+
+	// LOCAL 命令表示是代理自身发起的连接，例如健康检查，保留连接本身的地址
+	if cmd == 0 {
+		return nil
+	}
+
+	// 监听的是 TCP 连接，UDP 等非 STREAM 的传输协议不合法，UNSPEC 保留连接本身的地址
+	if family != 0 && transport != 1 {
+		return errInvalidHeader
+	}
+
+	switch family {
+	case 1:
+		// AF_INET: 源地址4字节，目标地址4字节，源端口2字节，目标端口2字节
+		if len(payload) < 12 {
+			return errInvalidHeader
+		}
+		c.remoteAddr = &net.TCPAddr{
+			IP:   net.IP(payload[0:4]),
+			Port: int(binary.BigEndian.Uint16(payload[8:10])),
+		}
+		c.localAddr = &net.TCPAddr{
+			IP:   net.IP(payload[4:8]),
+			Port: int(binary.BigEndian.Uint16(payload[10:12])),
+		}
+	case 2:
+		// AF_INET6: 源地址16字节，目标地址16字节，源端口2字节，目标端口2字节
+		if len(payload) < 36 {
+			return errInvalidHeader
+		}
+		c.remoteAddr = &net.TCPAddr{
+			IP:   net.IP(payload[0:16]),
+			Port: int(binary.BigEndian.Uint16(payload[32:34])),
+		}
+		c.localAddr = &net.TCPAddr{
+			IP:   net.IP(payload[16:32]),
+			Port: int(binary.BigEndian.Uint16(payload[34:36])),
+		}
+	}
+
+	// 其余协议族(例如 AF_UNIX)无法表示为 TCP 地址，保留连接本身的地址
+
+	return nil
+}
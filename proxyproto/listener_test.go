@@ -0,0 +1,141 @@
+package proxyproto
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConn_readHeader(t *testing.T) {
+
+	v2 := func(cmd, family byte, payload []byte) []byte {
+		header := append([]byte{}, v2Signature...)
+		header = append(header, 0x20|cmd, family<<4|1, 0, 0)
+		binary.BigEndian.PutUint16(header[14:], uint16(len(payload)))
+		return append(header, payload...)
+	}
+
+	v2dgram := func(payload []byte) []byte {
+		header := append([]byte{}, v2Signature...)
+		header = append(header, 0x21, 1<<4|2, 0, 0)
+		binary.BigEndian.PutUint16(header[14:], uint16(len(payload)))
+		return append(header, payload...)
+	}
+
+	testCases := []struct {
+		name       string
+		header     []byte
+		wantRemote string
+		wantErr    bool
+	}{
+		{
+			name:       "v1 tcp4",
+			header:     []byte("PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\n"),
+			wantRemote: "192.168.0.1:56324",
+		},
+		{
+			name:       "v1 tcp6",
+			header:     []byte("PROXY TCP6 2001:db8::1 2001:db8::2 56324 443\r\n"),
+			wantRemote: "[2001:db8::1]:56324",
+		},
+		{
+			name:       "v1 unknown",
+			header:     []byte("PROXY UNKNOWN\r\n"),
+			wantRemote: "pipe",
+		},
+		{
+			name:    "v1 family mismatch",
+			header:  []byte("PROXY TCP4 2001:db8::1 192.168.0.11 56324 443\r\n"),
+			wantErr: true,
+		},
+		{
+			name:    "v1 invalid port",
+			header:  []byte("PROXY TCP4 192.168.0.1 192.168.0.11 70000 443\r\n"),
+			wantErr: true,
+		},
+		{
+			name:       "v2 inet",
+			header:     v2(1, 1, []byte{10, 0, 0, 1, 10, 0, 0, 2, 0x1f, 0x90, 0x01, 0xbb}),
+			wantRemote: "10.0.0.1:8080",
+		},
+		{
+			name: "v2 inet6",
+			header: v2(1, 2, append(append(
+				net.ParseIP("2001:db8::1").To16(),
+				net.ParseIP("2001:db8::2").To16()...),
+				0x1f, 0x90, 0x01, 0xbb)),
+			wantRemote: "[2001:db8::1]:8080",
+		},
+		{
+			name:       "v2 local",
+			header:     v2(0, 0, nil),
+			wantRemote: "pipe",
+		},
+		{
+			name:    "v2 dgram",
+			header:  v2dgram([]byte{10, 0, 0, 1, 10, 0, 0, 2, 0x1f, 0x90, 0x01, 0xbb}),
+			wantErr: true,
+		},
+		{
+			name:    "v2 short payload",
+			header:  v2(1, 1, []byte{10, 0, 0, 1}),
+			wantErr: true,
+		},
+		{
+			name:    "missing header",
+			header:  []byte("GET / HTTP/1.1\r\n\r\n"),
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server, client := net.Pipe()
+			defer server.Close()
+			defer client.Close()
+
+			go func() {
+				_, _ = client.Write(tc.header)
+				_, _ = client.Write([]byte("hello"))
+			}()
+
+			conn := newConn(server, time.Second)
+
+			buf := make([]byte, 5)
+			_, err := io.ReadFull(conn, buf)
+
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, "hello", string(buf))
+			assert.Equal(t, tc.wantRemote, conn.RemoteAddr().String())
+		})
+	}
+}
+
+func TestListener_isTrusted(t *testing.T) {
+	l := NewListener(nil, WithTrustedCIDRs("10.0.0.0/8", "2001:db8::/32"))
+
+	assert.True(t, l.isTrusted(&net.TCPAddr{IP: net.ParseIP("10.1.2.3")}))
+	assert.True(t, l.isTrusted(&net.TCPAddr{IP: net.ParseIP("2001:db8::5")}))
+	assert.False(t, l.isTrusted(&net.TCPAddr{IP: net.ParseIP("192.168.0.1")}))
+
+	l = NewListener(nil, WithTrustedCIDRs("192.168.0.1"))
+	assert.True(t, l.isTrusted(&net.TCPAddr{IP: net.ParseIP("192.168.0.1")}))
+	assert.False(t, l.isTrusted(&net.TCPAddr{IP: net.ParseIP("192.168.0.2")}))
+
+	// 没有配置可信地址时不信任任何来源
+	l = NewListener(nil)
+	assert.False(t, l.isTrusted(&net.TCPAddr{IP: net.ParseIP("10.1.2.3")}))
+
+	assert.Panics(t, func() {
+		NewListener(nil, WithTrustedCIDRs("10.0.0.0/33"))
+	})
+}
@@ -0,0 +1,192 @@
+package web
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+)
+
+// responseWriter 包装 http.ResponseWriter
+// 记录响应头是否已经提交、响应码以及写入的字节数，避免框架重复输出
+// 只有原始的 http.ResponseWriter 实现了 Flusher、Hijacker 和 ReaderFrom 时才透传，见 wrap
+type responseWriter struct {
+	http.ResponseWriter
+
+	ctx *Context
+
+	status      int
+	size        int64
+	wroteHeader bool
+	hijacked    bool
+}
+
+// reset 重新设置原始的 http.ResponseWriter，返回的包装只实现原始 http.ResponseWriter 支持的可选接口
+func (w *responseWriter) reset(resp http.ResponseWriter, ctx *Context) http.ResponseWriter {
+	*w = responseWriter{
+		ResponseWriter: resp,
+		ctx:            ctx,
+	}
+	return w.wrap()
+}
+
+// WriteHeader 提交响应头，重复提交会被忽略
+// 提交的响应码会同步到 Context.RespStatus，使得中间件可以拿到真实的响应码
+// 1xx 的响应码（101 除外）只是中间响应，直接发送，不会提交响应头
+func (w *responseWriter) WriteHeader(code int) {
+	if w.wroteHeader || w.hijacked {
+		return
+	}
+
+	if code >= 100 && code <= 199 && code != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	w.wroteHeader = true
+	w.status = code
+	w.ctx.RespStatus = code
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(data []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	n, err := w.ResponseWriter.Write(data)
+	w.size += int64(n)
+
+	return n, err
+}
+
+func (w *responseWriter) flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *responseWriter) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errHijackNotSupported
+	}
+
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	w.hijacked = true
+
+	return conn, rw, nil
+}
+
+// readFrom 底层支持时透传，例如 http 包可以借此使用 sendfile
+func (w *responseWriter) readFrom(src io.Reader) (int64, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	n, err := w.ResponseWriter.(io.ReaderFrom).ReadFrom(src)
+	w.size += n
+
+	return n, err
+}
+
+// wrap 根据原始 http.ResponseWriter 支持的可选接口选择包装的类型
+// 避免调用者通过类型断言检测到实际并不支持的能力，例如 SSE 检查 http.Flusher
+// 包装的类型都只有一个指针字段，转换为接口时不会额外分配内存
+func (w *responseWriter) wrap() http.ResponseWriter {
+	_, f := w.ResponseWriter.(http.Flusher)
+	_, h := w.ResponseWriter.(http.Hijacker)
+	_, r := w.ResponseWriter.(io.ReaderFrom)
+
+	switch {
+	case f && h && r:
+		return flushHijackReadFromWriter{w}
+	case f && h:
+		return flushHijackWriter{w}
+	case f && r:
+		return flushReadFromWriter{w}
+	case h && r:
+		return hijackReadFromWriter{w}
+	case f:
+		return flushWriter{w}
+	case h:
+		return hijackWriter{w}
+	case r:
+		return readFromWriter{w}
+	default:
+		return w
+	}
+}
+
+type flushWriter struct{ *responseWriter }
+
+func (w flushWriter) Flush() { w.flush() }
+
+type hijackWriter struct{ *responseWriter }
+
+func (w hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return w.hijack() }
+
+type readFromWriter struct{ *responseWriter }
+
+func (w readFromWriter) ReadFrom(src io.Reader) (int64, error) { return w.readFrom(src) }
+
+type flushHijackWriter struct{ *responseWriter }
+
+func (w flushHijackWriter) Flush() { w.flush() }
+
+func (w flushHijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return w.hijack() }
+
+type flushReadFromWriter struct{ *responseWriter }
+
+func (w flushReadFromWriter) Flush() { w.flush() }
+
+func (w flushReadFromWriter) ReadFrom(src io.Reader) (int64, error) { return w.readFrom(src) }
+
+type hijackReadFromWriter struct{ *responseWriter }
+
+func (w hijackReadFromWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return w.hijack() }
+
+func (w hijackReadFromWriter) ReadFrom(src io.Reader) (int64, error) { return w.readFrom(src) }
+
+type flushHijackReadFromWriter struct{ *responseWriter }
+
+func (w flushHijackReadFromWriter) Flush() { w.flush() }
+
+func (w flushHijackReadFromWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return w.hijack() }
+
+func (w flushHijackReadFromWriter) ReadFrom(src io.Reader) (int64, error) { return w.readFrom(src) }
+
+// Unwrap 返回原始的 http.ResponseWriter，兼容 http.ResponseController
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *responseWriter) canFlush() bool {
+	_, ok := w.ResponseWriter.(http.Flusher)
+	return ok
+}
+
+// committed 响应头已经提交或者连接已经被接管，框架不能再输出
+func (w *responseWriter) committed() bool {
+	return w.wroteHeader || w.hijacked
+}
+
+// Committed 响应头是否已经提交，或者连接已经被接管
+// 提交之后框架不再输出 RespStatus 和 RespData
+func (c *Context) Committed() bool {
+	return c.writer.committed()
+}
+
+// BytesWritten 已经写入响应体的字节数
+func (c *Context) BytesWritten() int64 {
+	return c.writer.size
+}
@@ -2,6 +2,7 @@ package web
 
 import (
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 )
@@ -32,6 +33,9 @@ type iRouter interface {
 	addRoute(string, string, HandleFunc, ...Middleware)
 
 	matchRoute(string, string) (RouteInfo, bool)
+
+	// 获取能够匹配路径的所有请求方法
+	allowedMethods(string) []string
 }
 
 // 路由树节点
@@ -45,6 +49,7 @@ type node struct {
 
 	regexChild *node
 	regexp     string
+	reg        *regexp.Regexp
 
 	handler HandleFunc
 
@@ -53,6 +58,24 @@ type node struct {
 	mdls []Middleware
 
 	cacheMdls []Middleware
+	mdlsOnce  sync.Once
+
+	// chain 拼接好中间件的业务处理逻辑，只在第一次命中时拼接
+	chain     HandleFunc
+	chainOnce sync.Once
+}
+
+// handlerChain 返回拼接好中间件的业务处理逻辑
+// 同一个节点命中的中间件是固定的，因此只需要拼接一次
+func (n *node) handlerChain(mdls []Middleware) HandleFunc {
+	n.chainOnce.Do(func() {
+		root := n.handler
+		for i := len(mdls) - 1; i >= 0; i-- {
+			root = mdls[i](root)
+		}
+		n.chain = root
+	})
+	return n.chain
 }
 
 // getOrCreateChild 判断当前节点是否存在path为参数的子节点
@@ -69,6 +92,7 @@ func (n *node) getOrCreateChild(path string) *node {
 					path:      path,
 					paramName: param,
 					regexp:    exp,
+					reg:       regexp.MustCompile(exp),
 				}
 				n.regexChild = rChild
 			} else if rChild.path != path {
@@ -146,7 +170,7 @@ func (n *node) childOf(path string) (*node, bool, bool) {
 		// 优先进行正则匹配
 		if n.regexChild != nil {
 			rChild := n.regexChild
-			match := n.isMatch(path)
+			match := rChild.isMatch(path)
 			if match {
 				return rChild, true, true
 			}
@@ -173,13 +197,11 @@ func (n *node) childOf(path string) (*node, bool, bool) {
 // isMatch 判断路径是否满足正则匹配
 // 是否有必要设计成node的行为？？？
 func (n *node) isMatch(path string) bool {
-	matched, err := regexp.Match(n.regexp, []byte(path))
-
-	if err != nil {
+	if n.reg == nil {
 		return false
 	}
 
-	return matched
+	return n.reg.MatchString(path)
 }
 
 // 前缀树路由实现
@@ -264,13 +286,17 @@ func (r *trieRouter) matchRoute(method, path string) (RouteInfo, bool) {
 		return result, true
 	}
 
-	paths := strings.Split(path[1:], "/")
-
-	// mdlsC := root.findMiddlewares(paths, Conditions...)
-
 	cur := root
 
-	for _, p := range paths {
+	// 逐段匹配路径，避免每次请求都切分路径带来的内存分配
+	rest := path[1:]
+	for {
+		p := rest
+		i := strings.IndexByte(rest, '/')
+		if i >= 0 {
+			p = rest[:i]
+		}
+
 		child, isParam, ok := cur.childOf(p)
 		if !ok {
 			return result, false
@@ -279,20 +305,43 @@ func (r *trieRouter) matchRoute(method, path string) (RouteInfo, bool) {
 			result.addValue(child.paramName, p)
 		}
 		cur = child
+
+		if i < 0 {
+			break
+		}
+		rest = rest[i+1:]
 	}
 
 	result.info = cur
 
-	if cur.cacheMdls == nil {
+	// 只在第一次命中时查找，并发请求下也只查找一次
+	cur.mdlsOnce.Do(func() {
+		paths := strings.Split(path[1:], "/")
 		mdlsC := root.findMiddlewares(paths, Conditions...)
 		cur.cacheMdls = <-mdlsC
-	}
+	})
 
 	result.mdls = cur.cacheMdls
 
 	return result, true
 }
 
+// allowedMethods 返回能够匹配 path 的所有请求方法
+func (r *trieRouter) allowedMethods(path string) []string {
+	var res []string
+
+	for method := range r.trees {
+		route, ok := r.matchRoute(method, path)
+		if ok && route.info.handler != nil {
+			res = append(res, method)
+		}
+	}
+
+	sort.Strings(res)
+
+	return res
+}
+
 type qElem struct {
 	level int
 	elem  *node
@@ -366,3 +366,18 @@ func (n *node) equals(m *node) (string, bool) {
 	return "", true
 
 }
+
+func TestTrieRouter_matchRegexpChild(t *testing.T) {
+	mockHandler := func(*Context) {}
+
+	testRouter := &trieRouter{}
+	testRouter.addRoute("get", "/order/:id(^[0-9]+$)/:tab(^[a-z]+$)", mockHandler)
+
+	// 使用子节点自己的正则匹配，而不是父节点的正则
+	found, ok := testRouter.matchRoute("get", "/order/12/items")
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{"id": "12", "tab": "items"}, found.params)
+
+	_, ok = testRouter.matchRoute("get", "/order/12/34")
+	assert.False(t, ok)
+}
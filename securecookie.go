@@ -0,0 +1,216 @@
+package web
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrInvalidCookie cookie 被篡改，或者不是由当前的任何一个密钥生成的
+	ErrInvalidCookie = errors.New("web: cookie 校验失败")
+
+	errSecureCookieNotConfigured = errors.New("web: 没有通过 ServerWithSecureCookie 设置密钥")
+)
+
+// ServerWithSecureCookie 设置签名和加密 cookie 使用的密钥
+func ServerWithSecureCookie(sc *SecureCookie) Option {
+	return func(httpServer *DefaultHttpServer) {
+		httpServer.secureCookie = sc
+	}
+}
+
+// SecureCookie 使用 HMAC-SHA256 签名，使用 AES-256-GCM 加密 cookie 的值
+// 签名和加密使用的密钥都由传入的密钥派生，cookie 的名称参与签名和加密，值不能挪用到其他 cookie 上
+//
+// 轮换密钥时将新的密钥作为 current，旧的密钥放到 previous 中，
+// 新的 cookie 只使用 current，旧的 cookie 依旧可以通过校验，直到旧的密钥被移除
+type SecureCookie struct {
+	keys []secureKey
+}
+
+type secureKey struct {
+	hash []byte
+	aead cipher.AEAD
+}
+
+// NewSecureCookie 创建 SecureCookie，密钥为空时 panic
+func NewSecureCookie(current []byte, previous ...[]byte) *SecureCookie {
+	sc := &SecureCookie{}
+
+	for _, key := range append([][]byte{current}, previous...) {
+		if len(key) == 0 {
+			panic("web: SecureCookie 的密钥不能为空")
+		}
+
+		block, err := aes.NewCipher(deriveKey(key, "encrypt"))
+		if err != nil {
+			panic(err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			panic(err)
+		}
+
+		sc.keys = append(sc.keys, secureKey{
+			hash: deriveKey(key, "sign"),
+			aead: aead,
+		})
+	}
+
+	return sc
+}
+
+// deriveKey 为不同的用途派生不同的 32 字节密钥
+func deriveKey(key []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("web-secure-cookie-" + purpose))
+	return mac.Sum(nil)
+}
+
+// Sign 返回带有签名的值，值本身没有加密
+func (sc *SecureCookie) Sign(name, value string) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(value))
+	sig := sc.mac(sc.keys[0].hash, name, payload)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// Verify 校验 Sign 生成的值，依次尝试所有的密钥
+func (sc *SecureCookie) Verify(name, signed string) (string, error) {
+	payload, sigStr, ok := strings.Cut(signed, ".")
+	if !ok {
+		return "", ErrInvalidCookie
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(sigStr)
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+
+	for _, key := range sc.keys {
+		if hmac.Equal(sig, sc.mac(key.hash, name, payload)) {
+			value, err := base64.RawURLEncoding.DecodeString(payload)
+			if err != nil {
+				return "", ErrInvalidCookie
+			}
+			return string(value), nil
+		}
+	}
+
+	return "", ErrInvalidCookie
+}
+
+func (sc *SecureCookie) mac(key []byte, name, payload string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(name))
+	mac.Write([]byte{'|'})
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// Encrypt 返回加密之后的值，cookie 的名称作为附加数据参与认证
+func (sc *SecureCookie) Encrypt(name, value string) (string, error) {
+	aead := sc.keys[0].aead
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(value)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	data := aead.Seal(nonce, nonce, []byte(value), []byte(name))
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// Decrypt 解密 Encrypt 生成的值，依次尝试所有的密钥
+func (sc *SecureCookie) Decrypt(name, encrypted string) (string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+
+	for _, key := range sc.keys {
+		size := key.aead.NonceSize()
+		if len(data) < size {
+			return "", ErrInvalidCookie
+		}
+		value, err := key.aead.Open(nil, data[:size], data[size:], []byte(name))
+		if err == nil {
+			return string(value), nil
+		}
+	}
+
+	return "", ErrInvalidCookie
+}
+
+func (c *Context) secureCookie() (*SecureCookie, error) {
+	if c.srv == nil || c.srv.secureCookie == nil {
+		return nil, errSecureCookieNotConfigured
+	}
+	return c.srv.secureCookie, nil
+}
+
+// SetSignedCookie 写入签名的 cookie，客户端可以看到但是无法篡改 cookie 的值
+func (c *Context) SetSignedCookie(cookie *http.Cookie) error {
+	sc, err := c.secureCookie()
+	if err != nil {
+		return err
+	}
+
+	ck := *cookie
+	ck.Value = sc.Sign(ck.Name, ck.Value)
+	c.SetCookie(&ck)
+	return nil
+}
+
+// GetSignedCookie 读取 SetSignedCookie 写入的 cookie
+// cookie 不存在时返回 http.ErrNoCookie，校验失败时返回 ErrInvalidCookie
+func (c *Context) GetSignedCookie(name string) (string, error) {
+	sc, err := c.secureCookie()
+	if err != nil {
+		return "", err
+	}
+
+	ck, err := c.Req.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+
+	return sc.Verify(name, ck.Value)
+}
+
+// SetEncryptedCookie 写入加密的 cookie，客户端无法读取也无法篡改 cookie 的值
+func (c *Context) SetEncryptedCookie(cookie *http.Cookie) error {
+	sc, err := c.secureCookie()
+	if err != nil {
+		return err
+	}
+
+	ck := *cookie
+	if ck.Value, err = sc.Encrypt(ck.Name, ck.Value); err != nil {
+		return err
+	}
+	c.SetCookie(&ck)
+	return nil
+}
+
+// GetEncryptedCookie 读取 SetEncryptedCookie 写入的 cookie
+// cookie 不存在时返回 http.ErrNoCookie，解密失败时返回 ErrInvalidCookie
+func (c *Context) GetEncryptedCookie(name string) (string, error) {
+	sc, err := c.secureCookie()
+	if err != nil {
+		return "", err
+	}
+
+	ck, err := c.Req.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+
+	return sc.Decrypt(name, ck.Value)
+}
@@ -0,0 +1,65 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContext_SecureCookie(t *testing.T) {
+	oldKey, newKey := []byte("old-secret"), []byte("new-secret")
+
+	oldSrv := NewHttpServer("", ServerWithSecureCookie(NewSecureCookie(oldKey))).(*DefaultHttpServer)
+	newSrv := NewHttpServer("", ServerWithSecureCookie(NewSecureCookie(newKey, oldKey))).(*DefaultHttpServer)
+
+	// 使用旧的密钥写入
+	resp := httptest.NewRecorder()
+	ctx := NewContext(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+	ctx.srv = oldSrv
+	require.NoError(t, ctx.SetSignedCookie(&http.Cookie{Name: "lang", Value: "zh-CN"}))
+	require.NoError(t, ctx.SetEncryptedCookie(&http.Cookie{Name: "token", Value: "remember-me"}))
+
+	cookies := resp.Result().Cookies()
+	require.Len(t, cookies, 2)
+	assert.NotContains(t, cookies[1].Value, "remember-me")
+
+	read := func(srv *DefaultHttpServer, cookies ...*http.Cookie) *Context {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		for _, ck := range cookies {
+			req.AddCookie(ck)
+		}
+		ctx := NewContext(httptest.NewRecorder(), req)
+		ctx.srv = srv
+		return ctx
+	}
+
+	// 轮换之后旧的 cookie 依旧有效
+	ctx = read(newSrv, cookies...)
+	lang, err := ctx.GetSignedCookie("lang")
+	require.NoError(t, err)
+	assert.Equal(t, "zh-CN", lang)
+
+	token, err := ctx.GetEncryptedCookie("token")
+	require.NoError(t, err)
+	assert.Equal(t, "remember-me", token)
+
+	// 移除旧的密钥之后失效
+	ctx = read(NewHttpServer("", ServerWithSecureCookie(NewSecureCookie(newKey))).(*DefaultHttpServer), cookies...)
+	_, err = ctx.GetSignedCookie("lang")
+	assert.Equal(t, ErrInvalidCookie, err)
+
+	// 篡改的值以及挪用到其他名称的值都无法通过校验
+	ctx = read(newSrv,
+		&http.Cookie{Name: "lang", Value: cookies[0].Value + "x"},
+		&http.Cookie{Name: "session", Value: cookies[1].Value},
+	)
+	_, err = ctx.GetSignedCookie("lang")
+	assert.Equal(t, ErrInvalidCookie, err)
+	_, err = ctx.GetEncryptedCookie("session")
+	assert.Equal(t, ErrInvalidCookie, err)
+	_, err = ctx.GetEncryptedCookie("missing")
+	assert.Equal(t, http.ErrNoCookie, err)
+}
@@ -1,11 +1,24 @@
 package web
 
 import (
+	"context"
+	"errors"
+	"log"
 	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uzziahlin/web/i18n"
 )
 
-var _ HttpServer = &DefaultHttpServer{}
+var (
+	_ HttpServer     = &DefaultHttpServer{}
+	_ GracefulServer = &DefaultHttpServer{}
+)
 
 // Server 抽象 管理server的生命周期信息以及路由注册操作
 type Server interface {
@@ -15,10 +28,21 @@ type Server interface {
 	Use(method, path string, mdls ...Middleware)
 }
 
+// GracefulServer 支持优雅关闭和平滑重启的Server
+// 单独定义而不是加到 Server 中，避免已有的 Server 实现因为缺少方法而无法编译
+// NewHttpServer 返回的 HttpServer 可以断言为 GracefulServer
+type GracefulServer interface {
+	// Shutdown 优雅关闭，停止接收新连接并等待已有请求处理完成
+	Shutdown(ctx context.Context) error
+	// Restart 平滑重启，将监听套接字交给新进程后优雅关闭当前Server
+	Restart() error
+}
+
 // HttpServer 对 Httpserver 进行抽象，管理http相关的操作
 type HttpServer interface {
 	Server
 	http.Handler
+	Hooks
 	Get(string, HandleFunc)
 	Post(string, HandleFunc)
 }
@@ -31,20 +55,96 @@ type DefaultHttpServer struct {
 	mdls []Middleware
 
 	t TemplateEngine
+
+	// errHandler 统一的错误处理逻辑
+	errHandler ErrorHandler
+
+	// hooks 请求生命周期的回调
+	hooks hooks
+
+	// validator Bind 使用的校验器
+	validator *Validator
+
+	// codecs Bind、Negotiate 等使用的编解码器
+	codecs *CodecRegistry
+
+	// bodyLimit 全局的请求体大小限制，0 表示不限制
+	bodyLimit int64
+
+	// secureCookie 签名和加密 cookie 使用的密钥
+	secureCookie *SecureCookie
+
+	// flashStore flash 消息的存储
+	flashStore FlashStore
+
+	// trustedProxies 可信的代理，ClientIP 等方法只信任它们转发的请求头
+	trustedProxies []*net.IPNet
+
+	// catalog 翻译消息使用的消息目录
+	catalog *i18n.Catalog
+
+	// multipartMemory 解析 multipart 表单时保存在内存中的最大字节数
+	multipartMemory int64
+	// maxFileSize Bind 时单个文件的大小限制，0 表示不限制
+	maxFileSize int64
+
+	// root 拼接好全局中间件的处理逻辑，在创建Server时拼接一次
+	root HandleFunc
+
+	// pool 复用 Context，减少每个请求的内存分配
+	pool sync.Pool
+
+	srv *http.Server
+
+	mu       sync.Mutex
+	listener net.Listener
+
+	// wrappers 用于包装监听套接字，例如解析 PROXY protocol
+	wrappers []ListenerWrapper
+
+	// restartSignals 收到这些信号时触发平滑重启
+	restartSignals []os.Signal
+	// readyTimeout 平滑重启时等待子进程就绪的最长时间
+	readyTimeout time.Duration
+	// shutdownTimeout 平滑重启时旧进程等待已有请求处理完成的最长时间
+	shutdownTimeout time.Duration
 }
 
 type Option func(httpServer *DefaultHttpServer)
 
+// ListenerWrapper 对监听套接字进行包装，可以在连接交给 http 处理之前做一些额外处理
+type ListenerWrapper func(l net.Listener) net.Listener
+
+// ServerWithListenerWrapper 设置监听套接字的包装器，按照传入的顺序依次包装
+func ServerWithListenerWrapper(wrappers ...ListenerWrapper) Option {
+	return func(httpServer *DefaultHttpServer) {
+		httpServer.wrappers = append(httpServer.wrappers, wrappers...)
+	}
+}
+
 func NewHttpServer(addr string, opts ...Option) HttpServer {
 	server := &DefaultHttpServer{
-		addr:    addr,
-		iRouter: &trieRouter{},
+		addr:            addr,
+		iRouter:         &trieRouter{},
+		errHandler:      DefaultErrorHandler,
+		readyTimeout:    time.Second * 30,
+		shutdownTimeout: time.Second * 30,
+	}
+
+	server.srv = &http.Server{
+		Handler: server,
+	}
+
+	server.pool.New = func() any {
+		return &Context{}
 	}
 
 	for _, opt := range opts {
 		opt(server)
 	}
 
+	server.root = server.buildRoot()
+
 	return server
 }
 
@@ -61,28 +161,113 @@ func (s *DefaultHttpServer) Use(method, path string, mdls ...Middleware) {
 }
 
 // Start 启动Server
+// 如果当前进程是由平滑重启拉起的子进程，则直接复用父进程传递过来的监听套接字
 func (s *DefaultHttpServer) Start() error {
-	// 监听端口
-	l, err := net.Listen("tcp", s.addr)
+	if err := s.checkFlashStore(); err != nil {
+		return err
+	}
+
+	l, err := inheritedListener()
 	if err != nil {
 		return err
 	}
 
+	// 没有继承的套接字，监听端口
+	if l == nil {
+		l, err = net.Listen("tcp", s.addr)
+		if err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	s.listener = l
+	s.mu.Unlock()
+
 	// 中间可以有生命周期回调
 
-	return http.Serve(l, s)
+	if len(s.restartSignals) > 0 {
+		go s.watchRestart()
+	}
+
+	// 套接字已经就绪，通知父进程可以退出了
+	if err = notifyReady(); err != nil {
+		_ = l.Close()
+		return err
+	}
+
+	// 平滑重启需要传递原始的套接字，因此只对提供服务的套接字进行包装
+	for _, wrap := range s.wrappers {
+		l = wrap(l)
+	}
+
+	err = s.srv.Serve(l)
 
+	// 被优雅关闭不视为错误
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+// Shutdown 优雅关闭Server，停止接收新连接并等待已有请求处理完成
+func (s *DefaultHttpServer) Shutdown(ctx context.Context) error {
+	return s.srv.Shutdown(ctx)
+}
+
+// watchRestart 监听重启信号，收到信号后执行平滑重启
+func (s *DefaultHttpServer) watchRestart() {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, s.restartSignals...)
+	defer signal.Stop(c)
+
+	for range c {
+		err := s.Restart()
+		if err == nil {
+			return
+		}
+		// 重启失败，旧进程继续提供服务
+		log.Printf("web: 平滑重启失败: %v", err)
+	}
 }
 
 // ServeHTTP 作为请求入口，处理Http请求
 func (s *DefaultHttpServer) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
-	// todo 路由匹配，封装上下文，调用业务处理逻辑
-	ctx := &Context{
-		Req:  req,
-		Resp: resp,
-		T:    s.t,
+	ctx := s.pool.Get().(*Context)
+
+	ctx.Req = req
+	ctx.Resp = ctx.writer.reset(resp, ctx)
+	ctx.T = s.t
+	ctx.srv = s
+
+	if s.bodyLimit > 0 {
+		ctx.LimitBody(s.bodyLimit)
+	}
+
+	// 没有注册回调时不需要 recover，保留 panic 原本的调用栈
+	if len(s.hooks.panics) > 0 {
+		defer func() {
+			if v := recover(); v != nil {
+				s.hooks.firePanic(ctx, v)
+				panic(v)
+			}
+		}()
 	}
 
+	fire(ctx, s.hooks.requestStart)
+
+	s.root(ctx)
+
+	fire(ctx, s.hooks.responseWritten)
+
+	// 业务逻辑 panic 时不会走到这里，Context 直接丢弃，不放回池中
+	ctx.reset()
+	s.pool.Put(ctx)
+}
+
+// buildRoot 拼接全局中间件责任链
+func (s *DefaultHttpServer) buildRoot() HandleFunc {
 	root := s.Serve
 
 	// 拼接责任链
@@ -91,38 +276,32 @@ func (s *DefaultHttpServer) ServeHTTP(resp http.ResponseWriter, req *http.Reques
 	}
 
 	// 处理输出数据
-	root = func(next HandleFunc) HandleFunc {
-		return func(ctx *Context) {
-			defer func() {
-				ctx.Resp.WriteHeader(ctx.RespStatus)
-				_, _ = ctx.Resp.Write(ctx.RespData)
-			}()
-			next(ctx)
-		}
-	}(root)
-
-	root(ctx)
+	return func(ctx *Context) {
+		defer ctx.WriteResponse()
+		root(ctx)
+	}
 }
 
 func (s *DefaultHttpServer) Serve(ctx *Context) {
 	route, ok := s.matchRoute(ctx.Req.Method, ctx.Req.URL.Path)
 
 	if !ok || route.info.handler == nil {
-		ctx.Resp.WriteHeader(http.StatusNotFound)
-		_, _ = ctx.Resp.Write([]byte("resource not found"))
+		// 其他请求方法可以匹配，说明是请求方法不对
+		if methods := s.allowedMethods(ctx.Req.URL.Path); len(methods) > 0 {
+			ctx.Resp.Header().Set("Allow", strings.Join(methods, ", "))
+			ctx.HandleError(ErrMethodNotAllowed)
+			return
+		}
+		ctx.HandleError(ErrNotFound)
 		return
 	}
 
 	ctx.PathParams = route.params
 	ctx.MatchedRoute = route.info.route
 
-	root := route.info.handler
+	fire(ctx, s.hooks.routeMatched)
 
-	mdls := route.mdls
-
-	for i := len(mdls) - 1; i >= 0; i-- {
-		root = mdls[i](root)
-	}
+	root := route.info.handlerChain(route.mdls)
 
 	root(ctx)
 
@@ -0,0 +1,447 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// discardWriter 丢弃输出的 ResponseWriter，避免基准测试统计到 httptest 的内存分配
+type discardWriter struct {
+	header http.Header
+}
+
+func (w *discardWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *discardWriter) Write(data []byte) (int, error) {
+	return len(data), nil
+}
+
+func (w *discardWriter) WriteHeader(int) {}
+
+func newBenchmarkServer() HttpServer {
+	mdl := func(next HandleFunc) HandleFunc {
+		return func(ctx *Context) {
+			next(ctx)
+		}
+	}
+
+	server := NewHttpServer(":8080", MiddlewareOptionBuilder(mdl, mdl))
+
+	data := []byte("ok")
+
+	handler := func(ctx *Context) {
+		ctx.RespStatus = http.StatusOK
+		ctx.RespData = data
+	}
+
+	server.Get("/user/info", handler)
+	server.Get("/user/:id", handler)
+	server.Use(http.MethodGet, "/user/*", mdl)
+
+	return server
+}
+
+func TestDefaultHttpServer_ServeHTTPAllocs(t *testing.T) {
+	server := newBenchmarkServer()
+
+	testCases := []struct {
+		name      string
+		path      string
+		maxAllocs float64
+	}{
+		{
+			name:      "static route",
+			path:      "/user/info",
+			maxAllocs: 0,
+		},
+		{
+			name:      "param route",
+			path:      "/user/12",
+			maxAllocs: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			resp := &discardWriter{header: http.Header{}}
+
+			// 预热，让路由缓存中间件并且让池中有可用的 Context
+			server.ServeHTTP(resp, req)
+
+			allocs := testing.AllocsPerRun(100, func() {
+				server.ServeHTTP(resp, req)
+			})
+
+			// 池化失效时每次请求都会分配 Context，超过上限
+			assert.LessOrEqual(t, allocs, tc.maxAllocs)
+		})
+	}
+}
+
+func TestDefaultHttpServer_ServeHTTPReset(t *testing.T) {
+	server := newBenchmarkServer()
+
+	var params []map[string]string
+
+	server.Get("/order/:id", func(ctx *Context) {
+		params = append(params, ctx.PathParams)
+		ctx.RespStatus = http.StatusOK
+	})
+
+	server.Get("/order/list", func(ctx *Context) {
+		params = append(params, ctx.PathParams)
+		ctx.RespStatus = http.StatusOK
+	})
+
+	for _, path := range []string{"/order/12", "/order/list"} {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	}
+
+	// 复用的 Context 不能残留上一个请求的数据
+	assert.Equal(t, []map[string]string{{"id": "12"}, nil}, params)
+}
+
+func BenchmarkDefaultHttpServer_ServeHTTP(b *testing.B) {
+	server := newBenchmarkServer()
+
+	benchmarks := []struct {
+		name string
+		path string
+	}{
+		{
+			name: "static route",
+			path: "/user/info",
+		},
+		{
+			name: "param route",
+			path: "/user/12",
+		},
+		{
+			name: "not found",
+			path: "/order/12",
+		},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			req := httptest.NewRequest(http.MethodGet, bm.path, nil)
+			resp := &discardWriter{header: http.Header{}}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				server.ServeHTTP(resp, req)
+			}
+		})
+	}
+}
+
+func TestContext_Stream(t *testing.T) {
+	var status int
+
+	mdl := func(next HandleFunc) HandleFunc {
+		return func(ctx *Context) {
+			next(ctx)
+			status = ctx.RespStatus
+		}
+	}
+
+	server := NewHttpServer(":8080", MiddlewareOptionBuilder(mdl))
+
+	server.Get("/export", func(ctx *Context) {
+		ctx.Resp.Header().Set("Content-Type", "text/csv")
+		w := ctx.Stream(http.StatusCreated)
+		_, _ = w.WriteString("a,b\n")
+		assert.NoError(t, w.Flush())
+		// 再次开启不会重复提交响应头
+		_, _ = ctx.Stream(http.StatusOK).WriteString("1,2\n")
+		ctx.RespData = []byte("ignored")
+	})
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/export", nil))
+
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	assert.True(t, recorder.Flushed)
+	assert.Equal(t, "a,b\n1,2\n", recorder.Body.String())
+	assert.Equal(t, "text/csv", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, http.StatusCreated, status)
+}
+
+func TestDefaultHttpServer_HandleError(t *testing.T) {
+	server := NewHttpServer(":8080", ServerWithErrorHandler(func(ctx *Context, err error) {
+		DefaultErrorHandler(ctx, err)
+		if errors.Is(err, ErrNotFound) {
+			ctx.RespData = []byte("custom not found")
+		}
+	}))
+
+	server.Get("/user/:id", HandleErr(func(ctx *Context) error {
+		if ctx.PathParams["id"] == "0" {
+			return NewHTTPError(http.StatusBadRequest, "invalid_id", "invalid id")
+		}
+		return errors.New("db down")
+	}))
+
+	testCases := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantBody   string
+		wantAllow  string
+	}{
+		{
+			name:       "http error",
+			method:     http.MethodGet,
+			path:       "/user/0",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "invalid id",
+		},
+		{
+			name:       "internal error",
+			method:     http.MethodGet,
+			path:       "/user/1",
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   "Internal Server Error",
+		},
+		{
+			name:       "not found",
+			method:     http.MethodGet,
+			path:       "/order/1",
+			wantStatus: http.StatusNotFound,
+			wantBody:   "custom not found",
+		},
+		{
+			name:       "method not allowed",
+			method:     http.MethodPost,
+			path:       "/user/1",
+			wantStatus: http.StatusMethodNotAllowed,
+			wantBody:   "method not allowed",
+			wantAllow:  http.MethodGet,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, httptest.NewRequest(tc.method, tc.path, nil))
+
+			assert.Equal(t, tc.wantStatus, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+			assert.Equal(t, tc.wantAllow, recorder.Header().Get("Allow"))
+		})
+	}
+}
+
+func TestProblemErrorHandler(t *testing.T) {
+	server := NewHttpServer(":8080", ServerWithProblemDetails())
+
+	server.Get("/order/:id", HandleErr(func(ctx *Context) error {
+		return &Problem{
+			Type:       "https://example.com/probs/out-of-stock",
+			Title:      "Out of stock",
+			Status:     http.StatusConflict,
+			Extensions: map[string]any{"sku": "A1", "status": 0},
+		}
+	}))
+
+	testCases := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "problem",
+			path:       "/order/1",
+			wantStatus: http.StatusConflict,
+			wantBody:   `{"sku":"A1","status":409,"title":"Out of stock","type":"https://example.com/probs/out-of-stock"}`,
+		},
+		{
+			name:       "not found",
+			path:       "/user/1",
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"code":"not_found","detail":"resource not found","instance":"/user/1","status":404,"title":"Not Found","type":"about:blank"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			assert.Equal(t, tc.wantStatus, recorder.Code)
+			assert.Equal(t, ProblemContentType, recorder.Header().Get("Content-Type"))
+			assert.JSONEq(t, tc.wantBody, recorder.Body.String())
+		})
+	}
+}
+
+func TestDefaultHttpServer_DirectWrite(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "report.txt"), []byte("report"), 0o666))
+
+	var (
+		status int
+		size   int64
+	)
+
+	mdl := func(next HandleFunc) HandleFunc {
+		return func(ctx *Context) {
+			next(ctx)
+			status = ctx.RespStatus
+			size = ctx.BytesWritten()
+		}
+	}
+
+	server := NewHttpServer(":8080", MiddlewareOptionBuilder(mdl))
+
+	downloader := &FileDownloader{Dir: dir}
+	server.Get("/download", downloader.Handle())
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/download?file=report.txt", nil))
+
+	// http.ServeFile 直接输出之后，框架不会再次输出
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "report", recorder.Body.String())
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, int64(6), size)
+
+	// 文件不存在时交给 ErrorHandler 处理，不会设置下载的响应头
+	server = NewHttpServer(":8080", ServerWithProblemDetails())
+	server.Get("/download", downloader.Handle())
+
+	for _, file := range []string{"missing.txt", "."} {
+		recorder = httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/download?file="+file, nil))
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+		assert.Equal(t, ProblemContentType, recorder.Header().Get("Content-Type"))
+		assert.Empty(t, recorder.Header().Get("Content-Disposition"))
+		assert.Contains(t, recorder.Body.String(), `"code":"file_not_found"`)
+	}
+}
+
+// plainWriter 只实现 http.ResponseWriter
+type plainWriter struct {
+	http.ResponseWriter
+}
+
+func TestContext_ResponseWriterInterfaces(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	// 只透传原始 http.ResponseWriter 支持的接口
+	ctx := NewContext(httptest.NewRecorder(), req)
+	_, ok := ctx.Resp.(http.Flusher)
+	assert.True(t, ok)
+	_, ok = ctx.Resp.(http.Hijacker)
+	assert.False(t, ok)
+	_, ok = ctx.Resp.(io.ReaderFrom)
+	assert.False(t, ok)
+
+	ctx = NewContext(plainWriter{httptest.NewRecorder()}, req)
+	_, ok = ctx.Resp.(http.Flusher)
+	assert.False(t, ok)
+	assert.Equal(t, errFlushNotSupported, ctx.Stream(http.StatusOK).Flush())
+	_, _, err := ctx.Hijack()
+	assert.Equal(t, errHijackNotSupported, err)
+
+	// 1xx 的中间响应不会提交响应头
+	ctx = NewContext(httptest.NewRecorder(), req)
+	ctx.Resp.WriteHeader(http.StatusEarlyHints)
+	assert.False(t, ctx.Committed())
+	assert.Equal(t, 0, ctx.RespStatus)
+
+	ctx.Resp.WriteHeader(http.StatusCreated)
+	assert.True(t, ctx.Committed())
+	assert.Equal(t, http.StatusCreated, ctx.RespStatus)
+}
+
+func TestDefaultHttpServer_Hooks(t *testing.T) {
+	var events []string
+
+	server := NewHttpServer(":8080")
+
+	server.OnRequestStart(func(ctx *Context) {
+		events = append(events, "start "+ctx.Req.URL.Path)
+	})
+	server.OnRouteMatched(func(ctx *Context) {
+		events = append(events, "matched "+ctx.MatchedRoute)
+	})
+	server.OnError(func(ctx *Context, err error) {
+		events = append(events, "error")
+	})
+	server.OnPanic(func(ctx *Context, v any) {
+		events = append(events, fmt.Sprintf("panic %v", v))
+	})
+	server.OnResponseWritten(func(ctx *Context) {
+		events = append(events, fmt.Sprintf("written %d", ctx.RespStatus))
+	})
+
+	server.Get("/user/:id", func(ctx *Context) {
+		ctx.HandleError(&PanicError{Value: "recovered"})
+	})
+	server.Get("/panic", func(ctx *Context) {
+		panic("boom")
+	})
+
+	server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/user/1", nil))
+	server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/order", nil))
+
+	assert.PanicsWithValue(t, "boom", func() {
+		server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/panic", nil))
+	})
+
+	assert.Equal(t, []string{
+		"start /user/1", "matched /user/:id", "panic recovered", "error", "written 500",
+		"start /order", "error", "written 404",
+		"start /panic", "matched /panic", "panic boom",
+	}, events)
+}
+
+func TestContext_ContextContract(t *testing.T) {
+	type ctxKey struct{}
+
+	reqCtx, cancel := context.WithTimeout(context.WithValue(context.Background(), ctxKey{}, "req"), time.Minute)
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(reqCtx)
+	ctx := NewContext(httptest.NewRecorder(), req)
+	ctx.UserValues = map[string]any{"user": "tom"}
+
+	deadline, ok := ctx.Deadline()
+	assert.True(t, ok)
+	want, _ := reqCtx.Deadline()
+	assert.Equal(t, want, deadline)
+
+	assert.Equal(t, "tom", ctx.Value("user"))
+	assert.Equal(t, "req", ctx.Value(ctxKey{}))
+	assert.Nil(t, ctx.Value("missing"))
+
+	assert.NoError(t, ctx.Err())
+	cancel()
+	<-ctx.Done()
+	assert.Equal(t, context.Canceled, ctx.Err())
+
+	// 重置之后按照已经取消处理，而不是 panic
+	ctx.reset()
+	<-ctx.Done()
+	assert.Equal(t, context.Canceled, ctx.Err())
+	assert.Nil(t, ctx.Value("user"))
+}
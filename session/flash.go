@@ -0,0 +1,66 @@
+package session
+
+import (
+	"encoding/json"
+
+	"github.com/uzziahlin/web"
+)
+
+// FlashStore 使用 session 保存 flash 消息，通过 web.ServerWithFlashStore 设置
+type FlashStore struct {
+	Manager *Manager
+	// Key session 中保存消息使用的键，默认为 _flash
+	Key string
+}
+
+var _ web.FlashStore = FlashStore{}
+
+func (s FlashStore) key() string {
+	if s.Key == "" {
+		return "_flash"
+	}
+	return s.Key
+}
+
+func (s FlashStore) Save(ctx *web.Context, flashes []web.Flash) error {
+	sess, err := s.Manager.GetSession(ctx)
+	if err != nil {
+		return err
+	}
+
+	// 保存为字符串，兼容只能保存字符串的存储，例如 redis
+	data, err := json.Marshal(flashes)
+	if err != nil {
+		return err
+	}
+
+	return sess.Set(ctx.Req.Context(), s.key(), string(data))
+}
+
+// Consume 不同的存储在 key 不存在时返回的错误不同，因此读取失败时按照没有消息处理
+func (s FlashStore) Consume(ctx *web.Context) ([]web.Flash, error) {
+	sess, err := s.Manager.GetSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	val, err := sess.Get(ctx.Req.Context(), s.key())
+	if err != nil {
+		return nil, nil
+	}
+
+	data, _ := val.(string)
+	if data == "" {
+		return nil, nil
+	}
+
+	if err = sess.Set(ctx.Req.Context(), s.key(), ""); err != nil {
+		return nil, err
+	}
+
+	var flashes []web.Flash
+	if err = json.Unmarshal([]byte(data), &flashes); err != nil {
+		return nil, nil
+	}
+	return flashes, nil
+}
@@ -1,25 +1,36 @@
 package session
 
 import (
+	"sync"
+
 	"github.com/uzziahlin/web"
 )
 
 type Manager struct {
 	Store
 	Propagator
+	// SessCtxKey 在 Context 中缓存 session 使用的键名
+	// session 通过 web.Key 缓存，同时以该键名写入 UserValues，兼容直接读取 ctx.UserValues[SessCtxKey] 的代码
 	SessCtxKey string
+
+	keyOnce sync.Once
+	key     web.Key[Session]
+}
+
+// ctxKey 每个 Manager 使用自己的 web.Key，不同 Manager 之间不会冲突
+func (m *Manager) ctxKey() web.Key[Session] {
+	m.keyOnce.Do(func() {
+		m.key = web.NewKey[Session](m.SessCtxKey)
+	})
+	return m.key
 }
 
 func (m *Manager) GetSession(ctx *web.Context) (Session, error) {
 
-	sess, ok := ctx.UserValues[m.SessCtxKey]
+	key := m.ctxKey()
 
-	if ok {
-		return sess.(Session), nil
-	}
-
-	if ctx.UserValues == nil {
-		ctx.UserValues = make(map[string]any, 1)
+	if sess, ok := web.Get(ctx, key); ok {
+		return sess, nil
 	}
 
 	sessID, err := m.Extract(ctx.Req)
@@ -34,6 +45,10 @@ func (m *Manager) GetSession(ctx *web.Context) (Session, error) {
 		return nil, err
 	}
 
+	web.Set(ctx, key, session)
+	if ctx.UserValues == nil {
+		ctx.UserValues = make(map[string]any, 1)
+	}
 	ctx.UserValues[m.SessCtxKey] = session
 
 	return session, nil
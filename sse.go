@@ -0,0 +1,290 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event Server-Sent Events 中的一个事件
+type Event struct {
+	// ID 事件ID，客户端重连时会通过 Last-Event-ID 带回最后收到的事件ID
+	ID string
+	// Event 事件类型，为空时客户端按照 message 处理
+	Event string
+	// Data 事件数据，多行数据会拆分成多个 data 字段
+	Data string
+	// Retry 告诉客户端断线重连的等待时间
+	Retry time.Duration
+}
+
+var (
+	// fieldReplacer 去掉字段值中的换行
+	fieldReplacer = strings.NewReplacer("\r", "", "\n", "")
+	// lineReplacer 将 \r\n 和 \r 统一为 \n，SSE 中三者都表示换行
+	lineReplacer = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+)
+
+type SSEOption func(w *SSEWriter)
+
+// SSEWithHeartbeat 设置心跳间隔，小于等于0表示不发送心跳
+// 心跳可以避免代理因为连接长时间空闲而断开连接
+func SSEWithHeartbeat(interval time.Duration) SSEOption {
+	return func(w *SSEWriter) {
+		w.heartbeat = interval
+	}
+}
+
+// SSEWriter 输出 Server-Sent Events
+type SSEWriter struct {
+	ctx    *Context
+	stream *StreamWriter
+
+	mu sync.Mutex
+
+	heartbeat time.Duration
+}
+
+// SSE 开启 Server-Sent Events 输出，设置相应的响应头并提交响应
+func (c *Context) SSE(opts ...SSEOption) *SSEWriter {
+	header := c.Resp.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// 禁止 nginx 缓存响应
+	header.Set("X-Accel-Buffering", "no")
+
+	w := &SSEWriter{
+		ctx:       c,
+		stream:    c.Stream(http.StatusOK),
+		heartbeat: time.Second * 15,
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	_ = w.stream.Flush()
+
+	return w
+}
+
+// LastEventID 客户端重连时带上的最后收到的事件ID
+func (w *SSEWriter) LastEventID() string {
+	return w.ctx.Req.Header.Get("Last-Event-ID")
+}
+
+// Done 客户端断开连接或者请求被取消时关闭
+func (w *SSEWriter) Done() <-chan struct{} {
+	return w.ctx.Req.Context().Done()
+}
+
+// Send 发送一个事件，请求被取消后返回对应的错误
+func (w *SSEWriter) Send(e Event) error {
+	if err := w.ctx.Req.Context().Err(); err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+
+	if e.ID != "" {
+		writeField(&sb, "id", e.ID)
+	}
+
+	if e.Event != "" {
+		writeField(&sb, "event", e.Event)
+	}
+
+	if e.Retry > 0 {
+		writeField(&sb, "retry", strconv.FormatInt(e.Retry.Milliseconds(), 10))
+	}
+
+	for _, line := range strings.Split(lineReplacer.Replace(e.Data), "\n") {
+		writeField(&sb, "data", line)
+	}
+
+	// 空行表示一个事件结束
+	sb.WriteByte('\n')
+
+	return w.write(sb.String())
+}
+
+// Heartbeat 发送一个注释行作为心跳，客户端会忽略注释
+func (w *SSEWriter) Heartbeat() error {
+	if err := w.ctx.Req.Context().Err(); err != nil {
+		return err
+	}
+	return w.write(": ping\n\n")
+}
+
+// Run 持续发送 events 中的事件，并按照心跳间隔发送心跳
+// events 被关闭时返回 nil，请求被取消时返回对应的错误
+func (w *SSEWriter) Run(events <-chan Event) error {
+	var tick <-chan time.Time
+	if w.heartbeat > 0 {
+		ticker := time.NewTicker(w.heartbeat)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	done := w.Done()
+
+	for {
+		select {
+		case <-done:
+			return w.ctx.Req.Context().Err()
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if err := w.Send(e); err != nil {
+				return err
+			}
+		case <-tick:
+			if err := w.Heartbeat(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (w *SSEWriter) write(data string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, err := w.stream.WriteString(data); err != nil {
+		return err
+	}
+
+	return w.stream.Flush()
+}
+
+// writeField 写入一个字段，字段值中的换行会破坏事件格式，因此需要去掉
+func writeField(sb *strings.Builder, name, value string) {
+	sb.WriteString(name)
+	sb.WriteString(": ")
+	if name != "data" {
+		value = fieldReplacer.Replace(value)
+	}
+	sb.WriteString(value)
+	sb.WriteByte('\n')
+}
+
+type SSEHubOption func(h *SSEHub)
+
+// SSEHubWithHistory 设置保留的历史事件数量，用于客户端重连后根据 Last-Event-ID 补发事件
+func SSEHubWithHistory(size int) SSEHubOption {
+	return func(h *SSEHub) {
+		h.historySize = size
+	}
+}
+
+// SSEHubWithBuffer 设置每个订阅者的缓冲区大小
+// 订阅者消费不及时导致缓冲区满时，会被移除，客户端重连后根据 Last-Event-ID 补发事件
+func SSEHubWithBuffer(size int) SSEHubOption {
+	return func(h *SSEHub) {
+		h.bufferSize = size
+	}
+}
+
+// SSEHub 将事件广播给所有的订阅者
+type SSEHub struct {
+	mu sync.Mutex
+
+	subscribers map[chan Event]struct{}
+
+	history     []Event
+	historySize int
+
+	bufferSize int
+}
+
+func NewSSEHub(opts ...SSEHubOption) *SSEHub {
+	h := &SSEHub{
+		subscribers: map[chan Event]struct{}{},
+		historySize: 100,
+		bufferSize:  16,
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+// Publish 将事件广播给所有的订阅者，不会阻塞
+func (h *SSEHub) Publish(e Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.historySize > 0 && e.ID != "" {
+		h.history = append(h.history, e)
+		if len(h.history) > h.historySize {
+			h.history = h.history[len(h.history)-h.historySize:]
+		}
+	}
+
+	for ch := range h.subscribers {
+		select {
+		case ch <- e:
+		default:
+			// 订阅者消费太慢，直接移除
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribe 订阅事件，lastEventID 不为空时先补发该事件之后的历史事件
+// 返回的函数用于取消订阅，可以重复调用
+func (h *SSEHub) Subscribe(lastEventID string) (<-chan Event, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var replay []Event
+	if lastEventID != "" {
+		for i, e := range h.history {
+			if e.ID == lastEventID {
+				replay = h.history[i+1:]
+				break
+			}
+		}
+	}
+
+	// 补发的事件之外依旧保留 bufferSize 的空间，避免之后的第一个事件就因为缓冲区满而被移除
+	ch := make(chan Event, len(replay)+h.bufferSize)
+	for _, e := range replay {
+		ch <- e
+	}
+
+	h.subscribers[ch] = struct{}{}
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := h.subscribers[ch]; ok {
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Len 当前订阅者数量
+func (h *SSEHub) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subscribers)
+}
+
+// Serve 开启 SSE 输出并订阅事件，直到客户端断开连接
+func (h *SSEHub) Serve(ctx *Context, opts ...SSEOption) error {
+	w := ctx.SSE(opts...)
+
+	events, cancel := h.Subscribe(w.LastEventID())
+	defer cancel()
+
+	return w.Run(events)
+}
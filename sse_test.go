@@ -0,0 +1,174 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSSEContext(ctx context.Context) (*Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(http.MethodGet, "/events", nil).WithContext(ctx)
+	recorder := httptest.NewRecorder()
+	return NewContext(recorder, req), recorder
+}
+
+func TestSSEWriter_Send(t *testing.T) {
+	testCases := []struct {
+		name  string
+		event Event
+		want  string
+	}{
+		{
+			name:  "data only",
+			event: Event{Data: "hello"},
+			want:  "data: hello\n\n",
+		},
+		{
+			name:  "all fields",
+			event: Event{ID: "1", Event: "update", Data: "hello", Retry: time.Second * 3},
+			want:  "id: 1\nevent: update\nretry: 3000\ndata: hello\n\n",
+		},
+		{
+			name:  "multi-line data",
+			event: Event{Data: "a\nb\r\nc\rd"},
+			want:  "data: a\ndata: b\ndata: c\ndata: d\n\n",
+		},
+		{
+			name:  "newline in field",
+			event: Event{ID: "1\n2", Event: "up\r\ndate", Data: "x"},
+			want:  "id: 12\nevent: update\ndata: x\n\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, recorder := newSSEContext(context.Background())
+
+			w := ctx.SSE()
+			require.NoError(t, w.Send(tc.event))
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, "text/event-stream", recorder.Header().Get("Content-Type"))
+			assert.Equal(t, tc.want, recorder.Body.String())
+		})
+	}
+}
+
+func TestSSEWriter_Run(t *testing.T) {
+	reqCtx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+
+	ctx, recorder := newSSEContext(reqCtx)
+	w := ctx.SSE(SSEWithHeartbeat(time.Millisecond * 10))
+
+	events := make(chan Event, 1)
+	events <- Event{Data: "hello"}
+
+	// 请求被取消时返回对应的错误
+	assert.Equal(t, context.DeadlineExceeded, w.Run(events))
+	assert.Contains(t, recorder.Body.String(), "data: hello\n\n")
+	assert.Contains(t, recorder.Body.String(), ": ping\n\n")
+
+	assert.Equal(t, context.DeadlineExceeded, w.Send(Event{Data: "late"}))
+	assert.NotContains(t, recorder.Body.String(), "late")
+
+	// events 被关闭时返回 nil
+	ctx, _ = newSSEContext(context.Background())
+	closed := make(chan Event)
+	close(closed)
+	assert.NoError(t, ctx.SSE(SSEWithHeartbeat(0)).Run(closed))
+}
+
+func TestSSEHub_Subscribe(t *testing.T) {
+	hub := NewSSEHub(SSEHubWithHistory(2))
+
+	hub.Publish(Event{ID: "1", Data: "a"})
+	hub.Publish(Event{ID: "2", Data: "b"})
+	hub.Publish(Event{ID: "3", Data: "c"})
+
+	// 补发 Last-Event-ID 之后的历史事件
+	events, cancel := hub.Subscribe("2")
+	assert.Equal(t, Event{ID: "3", Data: "c"}, <-events)
+
+	hub.Publish(Event{ID: "4", Data: "d"})
+	assert.Equal(t, Event{ID: "4", Data: "d"}, <-events)
+
+	// 超出历史数量的事件无法补发
+	replay, cancelReplay := hub.Subscribe("1")
+	hub.Publish(Event{ID: "5", Data: "e"})
+	assert.Equal(t, Event{ID: "5", Data: "e"}, <-replay)
+	assert.Equal(t, Event{ID: "5", Data: "e"}, <-events)
+	cancelReplay()
+
+	assert.Equal(t, 1, hub.Len())
+	cancel()
+	cancel()
+	assert.Equal(t, 0, hub.Len())
+
+	_, ok := <-events
+	assert.False(t, ok)
+}
+
+func TestSSEHub_DropSlowSubscriber(t *testing.T) {
+	hub := NewSSEHub(SSEHubWithBuffer(1))
+
+	events, cancel := hub.Subscribe("")
+	defer cancel()
+
+	hub.Publish(Event{Data: "a"})
+	hub.Publish(Event{Data: "b"})
+
+	assert.Equal(t, 0, hub.Len())
+	assert.Equal(t, Event{Data: "a"}, <-events)
+	_, ok := <-events
+	assert.False(t, ok)
+}
+
+func TestSSEHub_ReplayKeepsBuffer(t *testing.T) {
+	hub := NewSSEHub(SSEHubWithBuffer(1))
+
+	for i := 0; i < 5; i++ {
+		hub.Publish(Event{ID: strconv.Itoa(i), Data: "a"})
+	}
+
+	// 补发的事件占满了原来的缓冲区时，之后的事件依旧可以送达
+	events, cancel := hub.Subscribe("0")
+	defer cancel()
+
+	hub.Publish(Event{ID: "5", Data: "b"})
+	assert.Equal(t, 1, hub.Len())
+
+	for i := 1; i <= 5; i++ {
+		e := <-events
+		assert.Equal(t, strconv.Itoa(i), e.ID)
+	}
+}
+
+func TestSSEHub_Serve(t *testing.T) {
+	hub := NewSSEHub()
+	hub.Publish(Event{ID: "1", Data: "a"})
+	hub.Publish(Event{ID: "2", Data: "b"})
+
+	reqCtx, cancel := context.WithCancel(context.Background())
+	ctx, recorder := newSSEContext(reqCtx)
+	ctx.Req.Header.Set("Last-Event-ID", "1")
+
+	done := make(chan error)
+	go func() {
+		done <- hub.Serve(ctx, SSEWithHeartbeat(0))
+	}()
+
+	assert.Eventually(t, func() bool { return hub.Len() == 1 }, time.Second, time.Millisecond)
+
+	// 客户端断开连接之后取消订阅
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+	assert.Equal(t, 0, hub.Len())
+	assert.Equal(t, "id: 2\ndata: b\n\n", recorder.Body.String())
+}
@@ -0,0 +1,60 @@
+package web
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+)
+
+var (
+	errFlushNotSupported  = errors.New("web: ResponseWriter 不支持 Flush")
+	errHijackNotSupported = errors.New("web: ResponseWriter 不支持 Hijack")
+)
+
+// StreamWriter 流式输出，写入的数据直接发送给客户端，不经过 RespData 缓存
+type StreamWriter struct {
+	ctx *Context
+}
+
+// Stream 开启流式输出
+// 第一次调用时以 status 提交响应头，之后再调用直接返回同一个 StreamWriter
+// 开启流式输出后 RespData 不再输出，RespStatus 为提交的响应码，中间件依旧可以读取
+// 响应头需要在调用 Stream 之前设置
+func (c *Context) Stream(status int) *StreamWriter {
+	if c.stream.ctx == nil {
+		c.stream.ctx = c
+		c.RespStatus = status
+		c.Resp.WriteHeader(status)
+	}
+	return &c.stream
+}
+
+func (w *StreamWriter) Write(data []byte) (int, error) {
+	return w.ctx.Resp.Write(data)
+}
+
+// WriteString 写入字符串
+func (w *StreamWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush 将已经写入的数据立刻发送给客户端
+func (w *StreamWriter) Flush() error {
+	if !w.ctx.writer.canFlush() {
+		return errFlushNotSupported
+	}
+	w.ctx.writer.flush()
+	return nil
+}
+
+// Hijack 接管底层连接，例如用于 WebSocket
+// 接管之后框架不再输出响应，连接的读写和关闭都由调用者负责
+func (c *Context) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := c.Resp.(http.Hijacker)
+	if !ok {
+		return nil, nil, errHijackNotSupported
+	}
+
+	return hijacker.Hijack()
+}
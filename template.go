@@ -16,16 +16,32 @@ type TemplateEngine interface {
 	Render(ctx context.Context, tplName string, data any) ([]byte, error)
 }
 
+// RequestFuncMap 根据请求生成模板函数，例如 FlashFuncMap
+type RequestFuncMap func(ctx context.Context) template.FuncMap
+
 type GoTemplateEngine struct {
 	Tpl *template.Template
+
+	// RequestFuncs 根据请求生成的模板函数，解析模板之前需要先注册同名的函数，例如 Tpl.Funcs(FlashFuncMap(nil))
+	// 每次渲染时复制 Tpl 并替换这些函数，html/template 执行过的模板不能复制，因此不能直接执行 Tpl
+	RequestFuncs []RequestFuncMap
 }
 
 func (g GoTemplateEngine) Render(ctx context.Context, tplName string, data any) ([]byte, error) {
-	buffer := bytes.Buffer{}
-
-	err := g.Tpl.ExecuteTemplate(&buffer, tplName, data)
+	tpl := g.Tpl
+
+	if len(g.RequestFuncs) > 0 {
+		var err error
+		if tpl, err = g.Tpl.Clone(); err != nil {
+			return nil, err
+		}
+		for _, fn := range g.RequestFuncs {
+			tpl.Funcs(fn(ctx))
+		}
+	}
 
-	if err != nil {
+	buffer := bytes.Buffer{}
+	if err := tpl.ExecuteTemplate(&buffer, tplName, data); err != nil {
 		return nil, err
 	}
 
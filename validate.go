@@ -0,0 +1,444 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+var errValidateFailed = NewHTTPError(http.StatusBadRequest, "validate_failed", "请求参数校验失败")
+
+// DefaultValidator 没有通过 ServerWithValidator 设置时使用的校验器
+var DefaultValidator = NewValidator()
+
+// ServerWithValidator 设置 Bind 和 BindJSON 使用的校验器
+func ServerWithValidator(v *Validator) Option {
+	return func(httpServer *DefaultHttpServer) {
+		httpServer.validator = v
+	}
+}
+
+// ValidateFunc 校验规则，param 为规则中 = 后面的参数，校验通过返回 true
+// 指针字段会先取出指向的值再调用，nil 指针只会执行 required 规则
+type ValidateFunc func(field reflect.Value, param string) bool
+
+// FieldError 单个字段校验失败
+type FieldError struct {
+	// Field 字段路径，例如 Name、Items[0].Price
+	Field string
+	// Rule 校验失败的规则
+	Rule string
+	// Param 规则的参数
+	Param string
+	// Value 字段的值
+	Value any
+}
+
+func (e *FieldError) Error() string {
+	if e.Param == "" {
+		return fmt.Sprintf("web: 字段 %s 不满足校验规则 %s", e.Field, e.Rule)
+	}
+	return fmt.Sprintf("web: 字段 %s 不满足校验规则 %s=%s", e.Field, e.Rule, e.Param)
+}
+
+// ValidationErrors 所有校验失败的字段
+type ValidationErrors []*FieldError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Validator 根据结构体字段的 validate 标签进行校验
+//
+//	type Order struct {
+//		Name   string  `validate:"required,min=2,max=20"`
+//		Email  string  `validate:"omitempty,email"`
+//		Status string  `validate:"oneof=paid unpaid"`
+//		Code   string  `validate:"regexp=^[A-Z]{2},[0-9]+$"`
+//		Items  []Item  `validate:"required,min=1"`
+//	}
+//
+// 多个规则以逗号分隔，regexp 规则必须放在最后，其后的内容都作为正则表达式
+// 嵌套的结构体，以及结构体的切片和指针会递归校验
+// 标签不合法时 Validate 返回错误，可以在启动时通过 Prepare 提前检查
+type Validator struct {
+	mu    sync.RWMutex
+	rules map[string]ValidateFunc
+
+	// cache 缓存每个结构体解析好的规则
+	cache sync.Map
+}
+
+func NewValidator() *Validator {
+	return &Validator{
+		rules: map[string]ValidateFunc{
+			"required": validateRequired,
+			"min":      validateMin,
+			"max":      validateMax,
+			"len":      validateLen,
+			"oneof":    validateOneOf,
+			"email":    validateEmail,
+			"url":      validateURL,
+		},
+	}
+}
+
+// Register 注册自定义的校验规则，同名规则会被覆盖
+func (v *Validator) Register(name string, fn ValidateFunc) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.rules[name] = fn
+}
+
+// Prepare 提前解析结构体的 validate 标签并缓存，嵌套的结构体同样会被解析
+// 标签不合法，例如正则表达式无法编译或者使用了未注册的规则时返回错误，一般在启动时调用
+func (v *Validator) Prepare(vals ...any) error {
+	for _, val := range vals {
+		if err := v.prepare(reflect.TypeOf(val), map[reflect.Type]bool{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *Validator) prepare(typ reflect.Type, seen map[reflect.Type]bool) error {
+	for typ != nil && (typ.Kind() == reflect.Pointer || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array) {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct || typ == timeType || seen[typ] {
+		return nil
+	}
+	seen[typ] = true
+
+	fields, err := v.cachedRules(typ)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		if err = v.prepare(typ.Field(f.index).Type, seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate 校验结构体，val 不是结构体或者结构体指针时直接返回 nil
+// 校验失败时返回 ValidationErrors，标签不合法时返回其他错误
+func (v *Validator) Validate(val any) error {
+	rv := reflect.ValueOf(val)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var errs ValidationErrors
+	if err := v.validateStruct(rv, "", &errs); err != nil {
+		return err
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+type rule struct {
+	name  string
+	param string
+	re    *regexp.Regexp
+}
+
+type validateField struct {
+	index     int
+	name      string
+	omitempty bool
+	rules     []rule
+}
+
+func (v *Validator) validateStruct(rv reflect.Value, prefix string, errs *ValidationErrors) error {
+	fields, err := v.cachedRules(rv.Type())
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fields {
+		path := f.name
+		if prefix != "" {
+			path = prefix + "." + f.name
+		}
+		if err = v.validateField(rv.Field(f.index), path, f, errs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *Validator) validateField(field reflect.Value, path string, f validateField, errs *ValidationErrors) error {
+	if f.omitempty && field.IsZero() {
+		return nil
+	}
+
+	elem := field
+	for elem.Kind() == reflect.Pointer && !elem.IsNil() {
+		elem = elem.Elem()
+	}
+
+	for _, r := range f.rules {
+		var ok bool
+		switch {
+		case r.name == "required":
+			ok = validateRequired(field, r.param)
+		case elem.Kind() == reflect.Pointer:
+			// nil 指针只执行 required
+			ok = true
+		case r.re != nil:
+			ok = elem.Kind() == reflect.String && r.re.MatchString(elem.String())
+		default:
+			ok = v.rule(r.name)(elem, r.param)
+		}
+
+		if !ok {
+			var value any
+			if elem.CanInterface() {
+				value = elem.Interface()
+			}
+			*errs = append(*errs, &FieldError{
+				Field: path,
+				Rule:  r.name,
+				Param: r.param,
+				Value: value,
+			})
+			// 同一个字段只报告第一个失败的规则
+			return nil
+		}
+	}
+
+	return v.validateNested(elem, path, errs)
+}
+
+// validateNested 递归校验嵌套的结构体以及结构体的切片
+func (v *Validator) validateNested(val reflect.Value, path string, errs *ValidationErrors) error {
+	switch val.Kind() {
+	case reflect.Struct:
+		if val.Type() != timeType {
+			return v.validateStruct(val, path, errs)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < val.Len(); i++ {
+			item := val.Index(i)
+			for item.Kind() == reflect.Pointer && !item.IsNil() {
+				item = item.Elem()
+			}
+			if item.Kind() != reflect.Struct {
+				continue
+			}
+			if err := v.validateNested(item, fmt.Sprintf("%s[%d]", path, i), errs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (v *Validator) rule(name string) ValidateFunc {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.rules[name]
+}
+
+// cachedRules 解析失败时不缓存，注册缺少的规则之后可以重新解析
+func (v *Validator) cachedRules(typ reflect.Type) ([]validateField, error) {
+	if fields, ok := v.cache.Load(typ); ok {
+		return fields.([]validateField), nil
+	}
+	fields, err := v.parseRules(typ)
+	if err != nil {
+		return nil, err
+	}
+	v.cache.Store(typ, fields)
+	return fields, nil
+}
+
+func (v *Validator) parseRules(typ reflect.Type) ([]validateField, error) {
+	var res []validateField
+
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		if !sf.IsExported() && !sf.Anonymous {
+			continue
+		}
+
+		f := validateField{
+			index: i,
+			name:  sf.Name,
+		}
+
+		tag := sf.Tag.Get("validate")
+		if tag == "-" {
+			continue
+		}
+
+		for tag != "" {
+			var part string
+			if strings.HasPrefix(tag, "regexp=") {
+				// 正则表达式中可能包含逗号，因此 regexp 之后的内容都作为正则表达式
+				part, tag = tag, ""
+			} else if idx := strings.IndexByte(tag, ','); idx >= 0 {
+				part, tag = tag[:idx], tag[idx+1:]
+			} else {
+				part, tag = tag, ""
+			}
+
+			name, param, _ := strings.Cut(part, "=")
+
+			switch {
+			case name == "omitempty":
+				f.omitempty = true
+				continue
+			case name == "regexp":
+				re, err := regexp.Compile(param)
+				if err != nil {
+					return nil, fmt.Errorf("web: 字段 %s.%s 的正则表达式不合法: %w", typ.Name(), sf.Name, err)
+				}
+				f.rules = append(f.rules, rule{name: name, param: param, re: re})
+				continue
+			case v.rule(name) == nil:
+				return nil, fmt.Errorf("web: 字段 %s.%s 使用了未注册的校验规则 %s", typ.Name(), sf.Name, name)
+			}
+
+			f.rules = append(f.rules, rule{name: name, param: param})
+		}
+
+		// 没有规则的字段依旧需要递归校验嵌套的结构体
+		res = append(res, f)
+	}
+
+	return res, nil
+}
+
+func validateRequired(field reflect.Value, _ string) bool {
+	switch field.Kind() {
+	case reflect.Slice, reflect.Map:
+		return field.Len() > 0
+	case reflect.Invalid:
+		return false
+	default:
+		return !field.IsZero()
+	}
+}
+
+// size 返回用于比较大小的值：数字为其本身，字符串为字符数，切片、map 为长度
+func size(field reflect.Value) (float64, bool) {
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(field.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(field.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return field.Float(), true
+	case reflect.String:
+		return float64(utf8.RuneCountInString(field.String())), true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(field.Len()), true
+	}
+	return 0, false
+}
+
+func compareSize(field reflect.Value, param string, cmp func(s, p float64) bool) bool {
+	s, ok := size(field)
+	if !ok {
+		return false
+	}
+	p, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return false
+	}
+	return cmp(s, p)
+}
+
+func validateMin(field reflect.Value, param string) bool {
+	return compareSize(field, param, func(s, p float64) bool { return s >= p })
+}
+
+func validateMax(field reflect.Value, param string) bool {
+	return compareSize(field, param, func(s, p float64) bool { return s <= p })
+}
+
+func validateLen(field reflect.Value, param string) bool {
+	return compareSize(field, param, func(s, p float64) bool { return s == p })
+}
+
+// validateOneOf 值必须是以空格分隔的参数之一
+func validateOneOf(field reflect.Value, param string) bool {
+	var val string
+	switch field.Kind() {
+	case reflect.String:
+		val = field.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		val = strconv.FormatInt(field.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		val = strconv.FormatUint(field.Uint(), 10)
+	default:
+		return false
+	}
+
+	for _, p := range strings.Fields(param) {
+		if p == val {
+			return true
+		}
+	}
+	return false
+}
+
+func validateEmail(field reflect.Value, _ string) bool {
+	if field.Kind() != reflect.String {
+		return false
+	}
+	addr, err := mail.ParseAddress(field.String())
+	// 不允许带名称的地址，例如 "Tom <tom@example.com>"
+	return err == nil && addr.Address == field.String()
+}
+
+func validateURL(field reflect.Value, _ string) bool {
+	if field.Kind() != reflect.String {
+		return false
+	}
+	u, err := url.Parse(field.String())
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
+// validate 使用 Server 设置的校验器校验 val
+func (c *Context) validate(val any) error {
+	v := DefaultValidator
+	if c.srv != nil && c.srv.validator != nil {
+		v = c.srv.validator
+	}
+
+	err := v.Validate(val)
+
+	var errs ValidationErrors
+	if errors.As(err, &errs) {
+		return errValidateFailed.WithError(err)
+	}
+
+	// 标签不合法属于服务端的错误，原样返回
+	return err
+}
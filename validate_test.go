@@ -0,0 +1,117 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type validateItem struct {
+	SKU   string `validate:"required,len=4"`
+	Count int    `validate:"min=1,max=99"`
+}
+
+type validateOrder struct {
+	Name    string         `validate:"required,min=2,max=5"`
+	Email   string         `validate:"omitempty,email"`
+	Site    *string        `validate:"omitempty,url"`
+	Status  string         `validate:"oneof=paid unpaid"`
+	Code    string         `validate:"regexp=^[A-Z]{2}[0-9]{1,3}$"`
+	Items   []validateItem `validate:"required"`
+	Gift    *validateItem  `validate:"required"`
+	Remark  string         `validate:"even"`
+	Address struct {
+		City string `validate:"required"`
+	}
+}
+
+func TestValidator_Validate(t *testing.T) {
+	v := NewValidator()
+	v.Register("even", func(field reflect.Value, _ string) bool {
+		return field.Len()%2 == 0
+	})
+
+	site := "not a url"
+
+	order := validateOrder{
+		Name:   "名字太长了吧",
+		Email:  "tom@example.com",
+		Site:   &site,
+		Status: "refund",
+		Code:   "AB1234",
+		Items: []validateItem{
+			{SKU: "A001", Count: 1},
+			{SKU: "A02", Count: 100},
+		},
+		Remark: "odd",
+	}
+
+	err := v.Validate(&order)
+
+	var errs ValidationErrors
+	require.True(t, errors.As(err, &errs))
+
+	got := make([]string, 0, len(errs))
+	for _, e := range errs {
+		got = append(got, e.Field+":"+e.Rule)
+	}
+
+	assert.Equal(t, []string{
+		"Name:max",
+		"Site:url",
+		"Status:oneof",
+		"Code:regexp",
+		"Items[1].SKU:len",
+		"Items[1].Count:max",
+		"Gift:required",
+		"Remark:even",
+		"Address.City:required",
+	}, got)
+
+	// 标签不合法时返回错误而不是 panic
+	var unknown struct {
+		Name string `validate:"unknown"`
+	}
+	err = v.Validate(unknown)
+	assert.ErrorContains(t, err, "未注册的校验规则 unknown")
+	assert.False(t, errors.As(err, &errs))
+
+	type badRegexp struct {
+		Code string `validate:"regexp=[a-"`
+	}
+	type wrapper struct {
+		Items []*badRegexp
+	}
+	assert.Error(t, v.Prepare(wrapper{}))
+	assert.NoError(t, v.Prepare(validateItem{}))
+
+	assert.NoError(t, v.Validate("not a struct"))
+}
+
+func TestContext_BindJSONValidate(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/order", strings.NewReader(`{"SKU":"A1","Count":1}`))
+	ctx := NewContext(httptest.NewRecorder(), req)
+
+	var item validateItem
+	err := ctx.BindJSON(&item)
+
+	var he *HTTPError
+	require.True(t, errors.As(err, &he))
+	assert.Equal(t, "validate_failed", he.Code)
+
+	// 标签不合法属于服务端的错误，不是 validate_failed
+	var bad struct {
+		SKU string `validate:"regexp=[a-"`
+	}
+	req = httptest.NewRequest(http.MethodPost, "/order", strings.NewReader(`{"SKU":"A1"}`))
+	ctx = NewContext(httptest.NewRecorder(), req)
+	err = ctx.BindJSON(&bad)
+	assert.Error(t, err)
+	assert.False(t, errors.As(err, &he))
+}
@@ -0,0 +1,190 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var errKeyNotFound = NewHTTPError(http.StatusBadRequest, "key_not_found", "key不存在")
+
+// StringValue 请求参数的值，以及获取它时发生的错误
+// 获取时出错，例如 key 不存在，所有的转换方法都会返回该错误
+type StringValue struct {
+	data string
+	err  error
+}
+
+// String 返回原始的字符串
+func (s StringValue) String() (string, error) {
+	return s.data, s.err
+}
+
+func (s StringValue) AsInt64() (int64, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+
+	return strconv.ParseInt(s.data, 10, 64)
+}
+
+func (s StringValue) AsInt() (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+
+	return strconv.Atoi(s.data)
+}
+
+func (s StringValue) AsUint64() (uint64, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+
+	return strconv.ParseUint(s.data, 10, 64)
+}
+
+func (s StringValue) AsFloat64() (float64, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+
+	return strconv.ParseFloat(s.data, 64)
+}
+
+// AsBool 支持 strconv.ParseBool 能够解析的值，例如 1、t、true、0、f、false
+func (s StringValue) AsBool() (bool, error) {
+	if s.err != nil {
+		return false, s.err
+	}
+
+	return strconv.ParseBool(s.data)
+}
+
+// AsDuration 按照 time.ParseDuration 的格式解析，例如 1h30m
+func (s StringValue) AsDuration() (time.Duration, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+
+	return time.ParseDuration(s.data)
+}
+
+// AsTime 按照 layout 解析时间，layout 为空时使用 RFC3339，unix 表示秒级时间戳
+func (s StringValue) AsTime(layout string) (time.Time, error) {
+	if s.err != nil {
+		return time.Time{}, s.err
+	}
+
+	return parseTime(s.data, layout)
+}
+
+// StringOrDefault 获取失败时返回 def，以下的 OrDefault 方法在获取或者转换失败时都返回 def
+func (s StringValue) StringOrDefault(def string) string {
+	if s.err != nil {
+		return def
+	}
+	return s.data
+}
+
+func (s StringValue) AsInt64OrDefault(def int64) int64 {
+	return orDefault(s.AsInt64())(def)
+}
+
+func (s StringValue) AsIntOrDefault(def int) int {
+	return orDefault(s.AsInt())(def)
+}
+
+func (s StringValue) AsUint64OrDefault(def uint64) uint64 {
+	return orDefault(s.AsUint64())(def)
+}
+
+func (s StringValue) AsFloat64OrDefault(def float64) float64 {
+	return orDefault(s.AsFloat64())(def)
+}
+
+func (s StringValue) AsBoolOrDefault(def bool) bool {
+	return orDefault(s.AsBool())(def)
+}
+
+func (s StringValue) AsDurationOrDefault(def time.Duration) time.Duration {
+	return orDefault(s.AsDuration())(def)
+}
+
+func (s StringValue) AsTimeOrDefault(layout string, def time.Time) time.Time {
+	return orDefault(s.AsTime(layout))(def)
+}
+
+func orDefault[T any](val T, err error) func(def T) T {
+	return func(def T) T {
+		if err != nil {
+			return def
+		}
+		return val
+	}
+}
+
+// StringValues 请求参数的所有值，例如 ?tag=a&tag=b
+// 任意一个值转换失败都会返回错误
+type StringValues struct {
+	data []string
+	err  error
+}
+
+// Strings 返回原始的字符串
+func (s StringValues) Strings() ([]string, error) {
+	return s.data, s.err
+}
+
+func (s StringValues) AsInt64s() ([]int64, error) {
+	return convertAll(s, func(val string) (int64, error) {
+		return strconv.ParseInt(val, 10, 64)
+	})
+}
+
+func (s StringValues) AsInts() ([]int, error) {
+	return convertAll(s, strconv.Atoi)
+}
+
+func (s StringValues) AsUint64s() ([]uint64, error) {
+	return convertAll(s, func(val string) (uint64, error) {
+		return strconv.ParseUint(val, 10, 64)
+	})
+}
+
+func (s StringValues) AsFloat64s() ([]float64, error) {
+	return convertAll(s, func(val string) (float64, error) {
+		return strconv.ParseFloat(val, 64)
+	})
+}
+
+func (s StringValues) AsBools() ([]bool, error) {
+	return convertAll(s, strconv.ParseBool)
+}
+
+func (s StringValues) AsDurations() ([]time.Duration, error) {
+	return convertAll(s, time.ParseDuration)
+}
+
+func (s StringValues) AsTimes(layout string) ([]time.Time, error) {
+	return convertAll(s, func(val string) (time.Time, error) {
+		return parseTime(val, layout)
+	})
+}
+
+func convertAll[T any](s StringValues, fn func(string) (T, error)) ([]T, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	res := make([]T, 0, len(s.data))
+	for _, val := range s.data {
+		v, err := fn(val)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, v)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,46 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStringValue(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/?n=12&f=1.5&b=true&d=1m&t=2023-01-02&bad=x&id=1&id=2", nil)
+	ctx := NewContext(httptest.NewRecorder(), req)
+	ctx.PathParams = map[string]string{"id": "7"}
+
+	n, err := ctx.GetQuery("n").AsInt()
+	require.NoError(t, err)
+	assert.Equal(t, 12, n)
+
+	u, err := ctx.PathValue("id").AsUint64()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7), u)
+
+	assert.Equal(t, 1.5, ctx.GetQuery("f").AsFloat64OrDefault(0))
+	assert.True(t, ctx.GetQuery("b").AsBoolOrDefault(false))
+	assert.Equal(t, time.Minute, ctx.GetQuery("d").AsDurationOrDefault(0))
+	assert.Equal(t, time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC), ctx.GetQuery("t").AsTimeOrDefault("2006-01-02", time.Time{}))
+
+	assert.Equal(t, 3, ctx.GetQuery("bad").AsIntOrDefault(3))
+	assert.Equal(t, "def", ctx.GetQuery("missing").StringOrDefault("def"))
+
+	_, err = ctx.GetQuery("missing").String()
+	assert.Equal(t, errKeyNotFound, err)
+
+	ids, err := ctx.GetQueryAll("id").AsInt64s()
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2}, ids)
+
+	_, err = ctx.GetQueryAll("bad").AsInts()
+	assert.Error(t, err)
+
+	_, err = ctx.GetQueryAll("missing").Strings()
+	assert.Equal(t, errKeyNotFound, err)
+}
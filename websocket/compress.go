@@ -0,0 +1,86 @@
+package websocket
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+	"strings"
+)
+
+// deflateTail permessage-deflate 压缩后去掉的结尾，解压时需要补上，参考 RFC 7692 7.2.1
+var deflateTail = []byte{0x00, 0x00, 0xff, 0xff}
+
+// compress 压缩一条消息，双方都不保留上下文，因此每条消息单独压缩
+func compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w, err := flate.NewWriter(&buf, flate.BestSpeed)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = w.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err = w.Flush(); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), deflateTail), nil
+}
+
+// decompress 解压一条消息，limit 大于0时限制解压后的大小
+func decompress(data []byte, limit int64) ([]byte, error) {
+	r := flate.NewReader(io.MultiReader(bytes.NewReader(data), bytes.NewReader(deflateTail)))
+	defer r.Close()
+
+	var src io.Reader = r
+	if limit > 0 {
+		src = io.LimitReader(r, limit+1)
+	}
+
+	res, err := io.ReadAll(src)
+	// 补上结尾后数据流并没有结束，读到结尾时会返回 io.ErrUnexpectedEOF
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, &protocolError{CloseInvalidPayloadData, "解压消息失败"}
+	}
+
+	if limit > 0 && int64(len(res)) > limit {
+		return nil, &protocolError{CloseMessageTooBig, "消息超过大小限制"}
+	}
+
+	return res, nil
+}
+
+// negotiateDeflate 解析客户端的 Sec-WebSocket-Extensions，判断是否可以开启 permessage-deflate
+// 服务端总是不保留上下文，并且要求客户端也不保留上下文
+// flate 只支持最大的窗口，因此客户端要求限制服务端窗口大小时不开启压缩
+func negotiateDeflate(header []string) bool {
+	for _, h := range header {
+		for _, ext := range strings.Split(h, ",") {
+			params := strings.Split(ext, ";")
+			if strings.TrimSpace(params[0]) != "permessage-deflate" {
+				continue
+			}
+
+			ok := true
+			for _, p := range params[1:] {
+				name := strings.TrimSpace(p)
+				if i := strings.IndexByte(name, '='); i >= 0 {
+					name = strings.TrimSpace(name[:i])
+				}
+				switch name {
+				case "server_no_context_takeover", "client_no_context_takeover", "client_max_window_bits":
+				default:
+					ok = false
+				}
+			}
+
+			if ok {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,528 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/uzziahlin/web"
+	"github.com/uzziahlin/web/session"
+)
+
+type MessageType int
+
+// 消息类型，与 RFC 6455 中的 opcode 一致
+const (
+	TextMessage   MessageType = 1
+	BinaryMessage MessageType = 2
+	CloseMessage  MessageType = 8
+	PingMessage   MessageType = 9
+	PongMessage   MessageType = 10
+
+	continuationFrame = 0
+)
+
+// 关闭码，参考 RFC 6455 7.4.1
+const (
+	CloseNormalClosure      = 1000
+	CloseGoingAway          = 1001
+	CloseProtocolError      = 1002
+	CloseUnsupportedData    = 1003
+	CloseNoStatusReceived   = 1005
+	CloseInvalidPayloadData = 1007
+	ClosePolicyViolation    = 1008
+	CloseMessageTooBig      = 1009
+	CloseInternalServerErr  = 1011
+)
+
+const (
+	finalBit = 0x80
+	rsv1Bit  = 0x40
+	rsvBits  = 0x70
+	maskBit  = 0x80
+
+	// maxControlPayload 控制帧的数据不能超过125字节
+	maxControlPayload = 125
+)
+
+var (
+	errWriteClosed = errors.New("websocket: 连接已经发送关闭帧")
+	errControlSize = errors.New("websocket: 控制帧数据不能超过125字节")
+)
+
+// CloseError 对端发送了关闭帧
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: 连接已关闭 %d %s", e.Code, e.Text)
+}
+
+// protocolError 对端违反协议，会以 Code 关闭连接
+type protocolError struct {
+	code int
+	msg  string
+}
+
+func (e *protocolError) Error() string {
+	return "websocket: " + e.msg
+}
+
+// Conn WebSocket 连接
+// 同一时刻只能有一个 goroutine 读取消息，写入消息可以并发调用
+// NextWriter 返回的 Writer 关闭之前，其他数据消息的写入会被阻塞，控制帧仍然可以穿插发送
+type Conn struct {
+	conn net.Conn
+	br   *bufio.Reader
+	bw   *bufio.Writer
+
+	ctx         *web.Context
+	sess        session.Session
+	subprotocol string
+
+	compress  bool
+	readLimit int64
+
+	// mmu 保证数据消息的分片不会交错，wmu 保证单个帧写入的完整
+	mmu        sync.Mutex
+	wmu        sync.Mutex
+	closeSent  bool
+	closeOnce  sync.Once
+	closeError error
+
+	pingHandler func(data []byte) error
+	pongHandler func(data []byte) error
+}
+
+func newConn(conn net.Conn, rw *bufio.ReadWriter, readLimit int64) *Conn {
+	if readLimit <= 0 {
+		readLimit = DefaultReadLimit
+	}
+
+	c := &Conn{
+		conn:      conn,
+		br:        rw.Reader,
+		bw:        rw.Writer,
+		readLimit: readLimit,
+	}
+
+	c.pingHandler = func(data []byte) error {
+		return c.WriteControl(PongMessage, data)
+	}
+	c.pongHandler = func([]byte) error {
+		return nil
+	}
+
+	return c
+}
+
+// Context 升级时的请求上下文，只能在 Handler 返回之前使用
+func (c *Conn) Context() *web.Context {
+	return c.ctx
+}
+
+// PathParam 获取路由中的路径参数
+func (c *Conn) PathParam(name string) string {
+	return c.ctx.PathParams[name]
+}
+
+// Session 升级时加载的 session，Upgrader 没有设置 SessionManager 时为 nil
+func (c *Conn) Session() session.Session {
+	return c.sess
+}
+
+// Subprotocol 协商出来的子协议
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// SetPingHandler 设置收到 ping 时的处理逻辑，默认回复 pong
+func (c *Conn) SetPingHandler(h func(data []byte) error) {
+	c.pingHandler = h
+}
+
+// SetPongHandler 设置收到 pong 时的处理逻辑，默认忽略
+func (c *Conn) SetPongHandler(h func(data []byte) error) {
+	c.pongHandler = h
+}
+
+// ReadMessage 读取一条完整的消息，分片的消息会被合并
+// 控制帧在读取过程中自动处理，对端关闭连接时返回 *CloseError
+func (c *Conn) ReadMessage() (MessageType, []byte, error) {
+	var (
+		typ        MessageType
+		data       []byte
+		compressed bool
+	)
+
+	for {
+		f, err := c.readFrame()
+		if err != nil {
+			return 0, nil, c.fail(err)
+		}
+
+		switch MessageType(f.opcode) {
+		case PingMessage:
+			if err = c.pingHandler(f.payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			if err = c.pongHandler(f.payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case CloseMessage:
+			return 0, nil, c.handleClose(f.payload)
+		case TextMessage, BinaryMessage:
+			if typ != 0 {
+				return 0, nil, c.fail(&protocolError{CloseProtocolError, "上一条消息还未结束"})
+			}
+			typ = MessageType(f.opcode)
+			compressed = f.rsv1
+		case continuationFrame:
+			if typ == 0 {
+				return 0, nil, c.fail(&protocolError{CloseProtocolError, "没有需要继续的消息"})
+			}
+		default:
+			return 0, nil, c.fail(&protocolError{CloseProtocolError, "未知的 opcode"})
+		}
+
+		if int64(len(data)+len(f.payload)) > c.readLimit {
+			return 0, nil, c.fail(&protocolError{CloseMessageTooBig, "消息超过大小限制"})
+		}
+
+		data = append(data, f.payload...)
+
+		if f.fin {
+			break
+		}
+	}
+
+	if compressed {
+		var err error
+		data, err = decompress(data, c.readLimit)
+		if err != nil {
+			return 0, nil, c.fail(err)
+		}
+	}
+
+	if typ == TextMessage && !utf8.Valid(data) {
+		return 0, nil, c.fail(&protocolError{CloseInvalidPayloadData, "文本消息不是合法的 UTF-8"})
+	}
+
+	return typ, data, nil
+}
+
+// WriteMessage 写入一条消息，开启压缩时会对消息进行压缩
+func (c *Conn) WriteMessage(typ MessageType, data []byte) error {
+	if typ != TextMessage && typ != BinaryMessage {
+		return c.WriteControl(typ, data)
+	}
+
+	if c.compress {
+		compressed, err := compress(data)
+		if err != nil {
+			return err
+		}
+		data = compressed
+	}
+
+	c.mmu.Lock()
+	defer c.mmu.Unlock()
+
+	return c.writeFrame(true, c.compress, byte(typ), data)
+}
+
+// NextWriter 返回一个按照分片写入消息的 Writer，每次 Write 发送一个分片，Close 时结束消息
+// 分片写入的消息不压缩，Writer 关闭之前其他数据消息的写入会被阻塞，因此使用完之后必须调用 Close
+func (c *Conn) NextWriter(typ MessageType) (io.WriteCloser, error) {
+	if typ != TextMessage && typ != BinaryMessage {
+		return nil, fmt.Errorf("websocket: 不能分片写入类型为 %d 的消息", typ)
+	}
+	c.mmu.Lock()
+	return &fragmentWriter{conn: c, opcode: byte(typ)}, nil
+}
+
+// WriteControl 写入控制帧
+func (c *Conn) WriteControl(typ MessageType, data []byte) error {
+	if typ != CloseMessage && typ != PingMessage && typ != PongMessage {
+		return fmt.Errorf("websocket: %d 不是控制帧", typ)
+	}
+
+	if len(data) > maxControlPayload {
+		return errControlSize
+	}
+
+	return c.writeFrame(true, false, byte(typ), data)
+}
+
+// Ping 发送 ping
+func (c *Conn) Ping(data []byte) error {
+	return c.WriteControl(PingMessage, data)
+}
+
+// CloseWithReason 发送关闭帧，之后不能再写入消息
+func (c *Conn) CloseWithReason(code int, reason string) error {
+	return c.WriteControl(CloseMessage, closePayload(code, reason))
+}
+
+// Close 发送正常关闭的关闭帧，并关闭底层连接
+func (c *Conn) Close() error {
+	c.closeOnce.Do(func() {
+		err := c.CloseWithReason(CloseNormalClosure, "")
+		if err != nil && err != errWriteClosed {
+			c.closeError = err
+		}
+		if err = c.conn.Close(); err != nil && c.closeError == nil {
+			c.closeError = err
+		}
+	})
+	return c.closeError
+}
+
+// handleClose 处理对端的关闭帧，回复相同的关闭码
+func (c *Conn) handleClose(payload []byte) error {
+	closeErr := &CloseError{Code: CloseNoStatusReceived}
+
+	if len(payload) == 1 {
+		return c.fail(&protocolError{CloseProtocolError, "关闭帧数据不合法"})
+	}
+
+	if len(payload) >= 2 {
+		closeErr.Code = int(binary.BigEndian.Uint16(payload))
+		closeErr.Text = string(payload[2:])
+		if !validCloseCode(closeErr.Code) {
+			return c.fail(&protocolError{CloseProtocolError, "关闭码不合法"})
+		}
+		if !utf8.ValidString(closeErr.Text) {
+			return c.fail(&protocolError{CloseInvalidPayloadData, "关闭原因不是合法的 UTF-8"})
+		}
+	}
+
+	replyCode := closeErr.Code
+	if replyCode == CloseNoStatusReceived {
+		replyCode = CloseNormalClosure
+	}
+
+	err := c.CloseWithReason(replyCode, "")
+	if err != nil && err != errWriteClosed {
+		return err
+	}
+
+	return closeErr
+}
+
+// fail 读取出错时，如果是协议错误，以对应的关闭码关闭连接
+func (c *Conn) fail(err error) error {
+	var pe *protocolError
+	if errors.As(err, &pe) {
+		_ = c.CloseWithReason(pe.code, "")
+	}
+	return err
+}
+
+type frame struct {
+	fin     bool
+	rsv1    bool
+	opcode  byte
+	payload []byte
+}
+
+func (c *Conn) readFrame() (frame, error) {
+	var f frame
+
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		return f, err
+	}
+
+	f.fin = header[0]&finalBit != 0
+	f.rsv1 = header[0]&rsv1Bit != 0
+	f.opcode = header[0] & 0x0f
+
+	rsv := header[0] & rsvBits
+	// 只有开启压缩时，数据消息的第一个分片才能设置 RSV1
+	if rsv != 0 && !(rsv == rsv1Bit && c.compress && (f.opcode == byte(TextMessage) || f.opcode == byte(BinaryMessage))) {
+		return f, &protocolError{CloseProtocolError, "RSV 位不合法"}
+	}
+
+	// 客户端发送的帧必须使用掩码
+	if header[1]&maskBit == 0 {
+		return f, &protocolError{CloseProtocolError, "客户端帧没有使用掩码"}
+	}
+
+	length := uint64(header[1] & 0x7f)
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return f, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return f, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+		if length>>63 != 0 {
+			return f, &protocolError{CloseProtocolError, "数据长度不合法"}
+		}
+	}
+
+	if f.opcode >= byte(CloseMessage) {
+		if length > maxControlPayload || !f.fin {
+			return f, &protocolError{CloseProtocolError, "控制帧不合法"}
+		}
+	}
+
+	// 分配内存之前检查长度，避免对端通过伪造的长度耗尽内存
+	if length > uint64(c.readLimit) {
+		return f, &protocolError{CloseMessageTooBig, "消息超过大小限制"}
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return f, err
+	}
+
+	f.payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, f.payload); err != nil {
+		return f, err
+	}
+
+	for i := range f.payload {
+		f.payload[i] ^= mask[i%4]
+	}
+
+	return f, nil
+}
+
+// writeFrame 写入一个帧，服务端发送的帧不使用掩码
+func (c *Conn) writeFrame(fin, rsv1 bool, opcode byte, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	if c.closeSent {
+		return errWriteClosed
+	}
+
+	if opcode == byte(CloseMessage) {
+		c.closeSent = true
+	}
+
+	var header [10]byte
+
+	header[0] = opcode
+	if fin {
+		header[0] |= finalBit
+	}
+	if rsv1 {
+		header[0] |= rsv1Bit
+	}
+
+	n := 2
+	length := len(payload)
+
+	switch {
+	case length <= 125:
+		header[1] = byte(length)
+	case length <= 0xffff:
+		header[1] = 126
+		binary.BigEndian.PutUint16(header[2:], uint16(length))
+		n += 2
+	default:
+		header[1] = 127
+		binary.BigEndian.PutUint64(header[2:], uint64(length))
+		n += 8
+	}
+
+	if _, err := c.bw.Write(header[:n]); err != nil {
+		return err
+	}
+
+	if _, err := c.bw.Write(payload); err != nil {
+		return err
+	}
+
+	return c.bw.Flush()
+}
+
+// fragmentWriter 分片写入消息
+type fragmentWriter struct {
+	conn   *Conn
+	opcode byte
+	closed bool
+}
+
+func (w *fragmentWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errWriteClosed
+	}
+
+	// 只有第一个分片携带消息类型，后续分片为继续帧
+	err := w.conn.writeFrame(false, false, w.opcode, p)
+	if err != nil {
+		return 0, err
+	}
+
+	w.opcode = continuationFrame
+
+	return len(p), nil
+}
+
+func (w *fragmentWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	defer w.conn.mmu.Unlock()
+	return w.conn.writeFrame(true, false, w.opcode, nil)
+}
+
+func closePayload(code int, reason string) []byte {
+	if code == CloseNoStatusReceived {
+		return nil
+	}
+
+	payload := make([]byte, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	copy(payload[2:], reason)
+
+	return payload
+}
+
+// validCloseCode 判断关闭码是否可以出现在关闭帧中
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003:
+		return true
+	case code >= 1007 && code <= 1011:
+		return true
+	case code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
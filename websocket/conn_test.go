@@ -0,0 +1,266 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uzziahlin/web"
+)
+
+// writeClientFrame 按照客户端的格式写入一个使用掩码的帧
+func writeClientFrame(t *testing.T, w io.Writer, header0 byte, payload []byte) {
+	mask := [4]byte{1, 2, 3, 4}
+
+	frame := []byte{header0}
+	switch {
+	case len(payload) <= 125:
+		frame = append(frame, maskBit|byte(len(payload)))
+	default:
+		frame = append(frame, maskBit|126, 0, 0)
+		binary.BigEndian.PutUint16(frame[2:], uint16(len(payload)))
+	}
+	frame = append(frame, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+
+	_, err := w.Write(frame)
+	require.NoError(t, err)
+}
+
+// readServerFrame 读取服务端发送的一个帧
+func readServerFrame(t *testing.T, r io.Reader) (byte, []byte) {
+	var header [2]byte
+	_, err := io.ReadFull(r, header[:])
+	require.NoError(t, err)
+
+	length := int(header[1] & 0x7f)
+	if length == 126 {
+		var ext [2]byte
+		_, err = io.ReadFull(r, ext[:])
+		require.NoError(t, err)
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	}
+
+	payload := make([]byte, length)
+	_, err = io.ReadFull(r, payload)
+	require.NoError(t, err)
+
+	return header[0], payload
+}
+
+func dial(t *testing.T, srv *httptest.Server, path, extensions string) (net.Conn, *bufio.Reader, *http.Response) {
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	require.NoError(t, err)
+
+	req := "GET " + path + " HTTP/1.1\r\n" +
+		"Host: " + strings.TrimPrefix(srv.URL, "http://") + "\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n" +
+		"Sec-WebSocket-Version: 13\r\n" +
+		"Sec-WebSocket-Protocol: chat, echo\r\n"
+	if extensions != "" {
+		req += "Sec-WebSocket-Extensions: " + extensions + "\r\n"
+	}
+	req += "\r\n"
+
+	_, err = conn.Write([]byte(req))
+	require.NoError(t, err)
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	require.NoError(t, err)
+
+	return conn, br, resp
+}
+
+func newEchoServer() *httptest.Server {
+	server := web.NewHttpServer(":8080")
+
+	upgrader := &Upgrader{
+		Subprotocols:      []string{"echo"},
+		EnableCompression: true,
+		ReadLimit:         1024,
+	}
+
+	server.Get("/echo/:room", upgrader.Handle(func(conn *Conn) {
+		for {
+			typ, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			data = append([]byte(conn.PathParam("room")+":"), data...)
+			if err = conn.WriteMessage(typ, data); err != nil {
+				return
+			}
+		}
+	}))
+
+	return httptest.NewServer(server)
+}
+
+func TestUpgrader_Handle(t *testing.T) {
+	srv := newEchoServer()
+	defer srv.Close()
+
+	conn, br, resp := dial(t, srv, "/echo/lobby", "")
+	defer conn.Close()
+
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", resp.Header.Get("Sec-WebSocket-Accept"))
+	assert.Equal(t, "echo", resp.Header.Get("Sec-WebSocket-Protocol"))
+	assert.Empty(t, resp.Header.Get("Sec-WebSocket-Extensions"))
+
+	// 分片发送的消息中间插入 ping
+	writeClientFrame(t, conn, byte(TextMessage), []byte("hel"))
+	writeClientFrame(t, conn, finalBit|byte(PingMessage), []byte("p"))
+	writeClientFrame(t, conn, finalBit|continuationFrame, []byte("lo"))
+
+	header, payload := readServerFrame(t, br)
+	assert.Equal(t, finalBit|byte(PongMessage), header)
+	assert.Equal(t, "p", string(payload))
+
+	header, payload = readServerFrame(t, br)
+	assert.Equal(t, finalBit|byte(TextMessage), header)
+	assert.Equal(t, "lobby:hello", string(payload))
+
+	// 超过大小限制
+	writeClientFrame(t, conn, finalBit|byte(BinaryMessage), make([]byte, 2048))
+
+	header, payload = readServerFrame(t, br)
+	assert.Equal(t, finalBit|byte(CloseMessage), header)
+	assert.Equal(t, CloseMessageTooBig, int(binary.BigEndian.Uint16(payload)))
+}
+
+func TestUpgrader_HandleCompression(t *testing.T) {
+	srv := newEchoServer()
+	defer srv.Close()
+
+	conn, br, resp := dial(t, srv, "/echo/lobby", "permessage-deflate; client_max_window_bits")
+	defer conn.Close()
+
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Sec-WebSocket-Extensions"), "permessage-deflate")
+
+	data, err := compress([]byte("hello"))
+	require.NoError(t, err)
+
+	writeClientFrame(t, conn, finalBit|rsv1Bit|byte(TextMessage), data)
+
+	header, payload := readServerFrame(t, br)
+	assert.Equal(t, finalBit|rsv1Bit|byte(TextMessage), header)
+
+	payload, err = decompress(payload, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "lobby:hello", string(payload))
+
+	writeClientFrame(t, conn, finalBit|byte(CloseMessage), closePayload(CloseGoingAway, "bye"))
+
+	header, payload = readServerFrame(t, br)
+	assert.Equal(t, finalBit|byte(CloseMessage), header)
+	assert.Equal(t, CloseGoingAway, int(binary.BigEndian.Uint16(payload)))
+}
+
+func TestUpgrader_Reject(t *testing.T) {
+	srv := newEchoServer()
+	defer srv.Close()
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/echo/lobby", nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept-Language", "en")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	require.NoError(t, err)
+
+	// 握手失败交给 ErrorHandler 处理，错误信息按照请求的语言翻译
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "not a websocket upgrade request", string(body))
+
+	// 使用 Problem Details 输出
+	server := web.NewHttpServer(":8080", web.ServerWithProblemDetails())
+	server.Post("/ws", (&Upgrader{}).Handle(func(conn *Conn) {}))
+	problemSrv := httptest.NewServer(server)
+	defer problemSrv.Close()
+
+	resp, err = http.Post(problemSrv.URL+"/ws", "text/plain", nil)
+	require.NoError(t, err)
+	body, err = io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	assert.Equal(t, web.ProblemContentType, resp.Header.Get("Content-Type"))
+	assert.Contains(t, string(body), `"code":"websocket_method_not_allowed"`)
+}
+
+func TestConn_OversizedFrameHeader(t *testing.T) {
+	server := web.NewHttpServer(":8080")
+	server.Get("/echo/:room", (&Upgrader{}).Handle(func(conn *Conn) {
+		_, _, _ = conn.ReadMessage()
+	}))
+	srv := httptest.NewServer(server)
+	defer srv.Close()
+
+	conn, br, resp := dial(t, srv, "/echo/lobby", "")
+	defer conn.Close()
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+	// 只发送声明了超大长度的帧头，服务端需要在分配内存之前拒绝
+	header := []byte{finalBit | byte(BinaryMessage), maskBit | 127, 0, 0, 0, 0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint64(header[2:], 1<<40)
+	_, err := conn.Write(header)
+	require.NoError(t, err)
+
+	h, payload := readServerFrame(t, br)
+	assert.Equal(t, finalBit|byte(CloseMessage), h)
+	assert.Equal(t, CloseMessageTooBig, int(binary.BigEndian.Uint16(payload)))
+}
+
+func TestConn_NextWriterBlocksMessages(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	c := newConn(server, bufio.NewReadWriter(bufio.NewReader(server), bufio.NewWriter(server)), 0)
+	assert.Equal(t, int64(DefaultReadLimit), c.readLimit)
+
+	frames := make(chan string, 4)
+	go func() {
+		br := bufio.NewReader(client)
+		for i := 0; i < 4; i++ {
+			_, payload := readServerFrame(t, br)
+			frames <- string(payload)
+		}
+	}()
+
+	w, err := c.NextWriter(TextMessage)
+	require.NoError(t, err)
+	_, err = w.Write([]byte("a"))
+	require.NoError(t, err)
+	assert.Equal(t, "a", <-frames)
+
+	done := make(chan struct{})
+	go func() {
+		assert.NoError(t, c.WriteMessage(TextMessage, []byte("other")))
+		close(done)
+	}()
+
+	_, err = w.Write([]byte("b"))
+	require.NoError(t, err)
+	assert.Equal(t, "b", <-frames)
+	require.NoError(t, w.Close())
+	assert.Equal(t, "", <-frames)
+
+	<-done
+	assert.Equal(t, "other", <-frames)
+}
@@ -0,0 +1,204 @@
+package websocket
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/uzziahlin/web"
+	"github.com/uzziahlin/web/session"
+)
+
+// acceptGUID 用于计算 Sec-WebSocket-Accept，参考 RFC 6455 1.3
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// DefaultReadLimit 没有设置 Upgrader.ReadLimit 时单条消息的最大字节数
+const DefaultReadLimit = 32 << 20
+
+var (
+	errMethodNotAllowed = web.NewHTTPError(http.StatusMethodNotAllowed, "websocket_method_not_allowed", "握手请求必须是 GET")
+	errNotUpgrade       = web.NewHTTPError(http.StatusBadRequest, "websocket_not_upgrade", "不是 WebSocket 升级请求")
+	errBadVersion       = web.NewHTTPError(http.StatusUpgradeRequired, "websocket_bad_version", "不支持的 WebSocket 协议版本")
+	errBadKey           = web.NewHTTPError(http.StatusBadRequest, "websocket_bad_key", "Sec-WebSocket-Key 不合法")
+	errOriginDenied     = web.NewHTTPError(http.StatusForbidden, "websocket_origin_denied", "Origin 不允许")
+	errSessionFailed    = web.NewHTTPError(http.StatusUnauthorized, "websocket_session_failed", "加载 session 失败")
+	errHijackFailed     = web.NewHTTPError(http.StatusInternalServerError, "websocket_hijack_failed", "接管连接失败")
+)
+
+// Handler 处理升级之后的 WebSocket 连接，返回后连接会被关闭
+type Handler func(conn *Conn)
+
+// Upgrader 将 http 请求升级为 WebSocket 连接
+type Upgrader struct {
+	// CheckOrigin 校验 Origin，为空时要求 Origin 的 host 与请求的 Host 一致
+	CheckOrigin func(ctx *web.Context) bool
+
+	// Subprotocols 服务端支持的子协议，按照客户端给出的顺序选择第一个支持的
+	Subprotocols []string
+
+	// EnableCompression 客户端支持时开启 permessage-deflate 压缩
+	EnableCompression bool
+
+	// ReadLimit 单条消息的最大字节数，解压之后的大小同样受该限制，小于等于0时使用 DefaultReadLimit
+	ReadLimit int64
+
+	// SessionManager 设置后会在升级之前加载 session，加载失败返回401
+	// 加载到的 session 可以通过 Conn.Session 获取
+	SessionManager *session.Manager
+}
+
+// Handle 返回可以注册到路由上的 HandleFunc
+func (u *Upgrader) Handle(h Handler) web.HandleFunc {
+	return func(ctx *web.Context) {
+		conn, err := u.Upgrade(ctx)
+		if err != nil {
+			return
+		}
+
+		defer conn.Close()
+
+		h(conn)
+	}
+}
+
+// Upgrade 完成握手并接管连接
+// 握手失败时将错误交给 ctx.HandleError 输出，并返回该错误
+func (u *Upgrader) Upgrade(ctx *web.Context) (*Conn, error) {
+	req := ctx.Req
+
+	if req.Method != http.MethodGet {
+		return nil, u.reject(ctx, errMethodNotAllowed)
+	}
+
+	if !headerContains(req.Header, "Connection", "upgrade") ||
+		!headerContains(req.Header, "Upgrade", "websocket") {
+		return nil, u.reject(ctx, errNotUpgrade)
+	}
+
+	if req.Header.Get("Sec-WebSocket-Version") != "13" {
+		ctx.Resp.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, u.reject(ctx, errBadVersion)
+	}
+
+	key := req.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, u.reject(ctx, errBadKey)
+	}
+
+	checkOrigin := u.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	if !checkOrigin(ctx) {
+		return nil, u.reject(ctx, errOriginDenied)
+	}
+
+	var sess session.Session
+	if u.SessionManager != nil {
+		var err error
+		sess, err = u.SessionManager.GetSession(ctx)
+		if err != nil {
+			return nil, u.reject(ctx, errSessionFailed.WithError(err))
+		}
+	}
+
+	subprotocol := u.selectSubprotocol(req)
+	compress := u.EnableCompression && negotiateDeflate(req.Header.Values("Sec-WebSocket-Extensions"))
+
+	netConn, rw, err := ctx.Hijack()
+	if err != nil {
+		return nil, u.reject(ctx, errHijackFailed.WithError(err))
+	}
+
+	var sb strings.Builder
+	sb.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	sb.WriteString("Upgrade: websocket\r\n")
+	sb.WriteString("Connection: Upgrade\r\n")
+	sb.WriteString("Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n")
+	if subprotocol != "" {
+		sb.WriteString("Sec-WebSocket-Protocol: " + subprotocol + "\r\n")
+	}
+	if compress {
+		sb.WriteString("Sec-WebSocket-Extensions: permessage-deflate; server_no_context_takeover; client_no_context_takeover\r\n")
+	}
+	// 保留中间件或者 session 设置的响应头，例如 Set-Cookie
+	for k, vs := range ctx.Resp.Header() {
+		for _, v := range vs {
+			sb.WriteString(k + ": " + v + "\r\n")
+		}
+	}
+	sb.WriteString("\r\n")
+
+	if _, err = rw.WriteString(sb.String()); err != nil {
+		_ = netConn.Close()
+		return nil, err
+	}
+	if err = rw.Flush(); err != nil {
+		_ = netConn.Close()
+		return nil, err
+	}
+
+	ctx.RespStatus = http.StatusSwitchingProtocols
+
+	conn := newConn(netConn, rw, u.ReadLimit)
+	conn.ctx = ctx
+	conn.sess = sess
+	conn.subprotocol = subprotocol
+	conn.compress = compress
+
+	return conn, nil
+}
+
+func (u *Upgrader) reject(ctx *web.Context, err *web.HTTPError) error {
+	ctx.HandleError(err)
+	return err
+}
+
+func (u *Upgrader) selectSubprotocol(req *http.Request) string {
+	for _, h := range req.Header.Values("Sec-WebSocket-Protocol") {
+		for _, p := range strings.Split(h, ",") {
+			p = strings.TrimSpace(p)
+			for _, supported := range u.Subprotocols {
+				if p == supported {
+					return p
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// sameOrigin 没有 Origin 的请求(非浏览器)直接放行，否则要求 Origin 的 host 与请求的 Host 一致
+func sameOrigin(ctx *web.Context) bool {
+	origin := ctx.Req.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(u.Host, ctx.Req.Host)
+}
+
+// headerContains 判断以逗号分隔的请求头中是否包含 token，忽略大小写
+func headerContains(header http.Header, name, token string) bool {
+	for _, h := range header.Values(name) {
+		for _, v := range strings.Split(h, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package webtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	"github.com/uzziahlin/web"
+)
+
+// Request 用于构造测试请求
+//
+//	resp := webtest.NewRequest(http.MethodGet, "/user/1").
+//		WithCookie(&http.Cookie{Name: "sessid", Value: "1"}).
+//		Do(server)
+type Request struct {
+	req    *http.Request
+	params map[string]string
+}
+
+// NewRequest 创建测试请求，target 可以是路径，也可以是完整的 URL
+func NewRequest(method, target string) *Request {
+	return &Request{
+		req: httptest.NewRequest(method, target, nil),
+	}
+}
+
+func (r *Request) WithHeader(key, value string) *Request {
+	r.req.Header.Add(key, value)
+	return r
+}
+
+func (r *Request) WithCookie(c *http.Cookie) *Request {
+	r.req.AddCookie(c)
+	return r
+}
+
+// WithQuery 追加查询参数
+func (r *Request) WithQuery(key, value string) *Request {
+	query := r.req.URL.Query()
+	query.Add(key, value)
+	r.req.URL.RawQuery = query.Encode()
+	r.req.RequestURI = r.req.URL.RequestURI()
+	return r
+}
+
+// WithBody 设置请求体以及对应的 Content-Type
+func (r *Request) WithBody(contentType string, body io.Reader) *Request {
+	rc, ok := body.(io.ReadCloser)
+	if !ok {
+		rc = io.NopCloser(body)
+	}
+	r.req.Body = rc
+	r.req.ContentLength = -1
+	if l, ok := body.(interface{ Len() int }); ok {
+		r.req.ContentLength = int64(l.Len())
+	}
+	r.req.Header.Set("Content-Type", contentType)
+	return r
+}
+
+// WithJSON 将 val 序列化为 json 作为请求体，序列化失败时 panic
+func (r *Request) WithJSON(val any) *Request {
+	data, err := json.Marshal(val)
+	if err != nil {
+		panic(err)
+	}
+	return r.WithBody("application/json", bytes.NewReader(data))
+}
+
+// WithForm 将表单作为请求体
+func (r *Request) WithForm(values url.Values) *Request {
+	return r.WithBody("application/x-www-form-urlencoded", strings.NewReader(values.Encode()))
+}
+
+// WithContext 设置请求的 context，例如用于测试超时和取消
+func (r *Request) WithContext(ctx context.Context) *Request {
+	r.req = r.req.WithContext(ctx)
+	return r
+}
+
+// WithPathParam 预置路径参数，只在 Call 中生效，Do 会由路由解析路径参数
+func (r *Request) WithPathParam(key, value string) *Request {
+	if r.params == nil {
+		r.params = make(map[string]string, 1)
+	}
+	r.params[key] = value
+	return r
+}
+
+// Request 返回构造好的 http.Request
+func (r *Request) Request() *http.Request {
+	return r.req
+}
+
+// Do 将请求交给 handler 处理，一般是 web.HttpServer
+func (r *Request) Do(handler http.Handler) *Response {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, r.req)
+	return &Response{ResponseRecorder: recorder}
+}
+
+// Call 不经过路由和中间件，直接调用单个 HandleFunc
+// 返回的 Response 中可以拿到调用时的 Context，用于检查 UserValues 等数据
+func (r *Request) Call(h web.HandleFunc) *Response {
+	recorder := httptest.NewRecorder()
+
+	ctx := web.NewContext(recorder, r.req)
+	ctx.PathParams = r.params
+
+	h(ctx)
+
+	ctx.WriteResponse()
+
+	return &Response{
+		ResponseRecorder: recorder,
+		Context:          ctx,
+	}
+}
@@ -0,0 +1,93 @@
+package webtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/uzziahlin/web"
+)
+
+// Response 测试请求的响应，提供常用的断言
+// 断言失败时调用 t.Errorf，不会中断测试，方法可以链式调用
+type Response struct {
+	*httptest.ResponseRecorder
+
+	// Context 通过 Call 调用时的请求上下文，通过 Do 调用时为 nil
+	Context *web.Context
+}
+
+// BodyString 以字符串返回响应体，内嵌的 ResponseRecorder.Body 依旧可以直接使用
+func (r *Response) BodyString() string {
+	return r.ResponseRecorder.Body.String()
+}
+
+// DecodeJSON 将响应体反序列化到 val 上
+func (r *Response) DecodeJSON(val any) error {
+	return json.Unmarshal(r.ResponseRecorder.Body.Bytes(), val)
+}
+
+// Cookie 获取响应中设置的 cookie，不存在返回 nil
+func (r *Response) Cookie(name string) *http.Cookie {
+	for _, c := range r.Result().Cookies() {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func (r *Response) AssertStatus(t testing.TB, want int) *Response {
+	t.Helper()
+	if r.Code != want {
+		t.Errorf("webtest: 响应码为 %d，期望 %d，响应体: %s", r.Code, want, r.BodyString())
+	}
+	return r
+}
+
+func (r *Response) AssertHeader(t testing.TB, key, want string) *Response {
+	t.Helper()
+	if got := r.Header().Get(key); got != want {
+		t.Errorf("webtest: 响应头 %s 为 %q，期望 %q", key, got, want)
+	}
+	return r
+}
+
+func (r *Response) AssertBody(t testing.TB, want string) *Response {
+	t.Helper()
+	if got := r.BodyString(); got != want {
+		t.Errorf("webtest: 响应体为 %q，期望 %q", got, want)
+	}
+	return r
+}
+
+// AssertJSON 判断响应体与 want 序列化之后的 json 是否等价，忽略字段顺序和空白
+func (r *Response) AssertJSON(t testing.TB, want any) *Response {
+	t.Helper()
+
+	data, err := json.Marshal(want)
+	if err != nil {
+		t.Errorf("webtest: 序列化期望值失败: %v", err)
+		return r
+	}
+
+	var wantVal, gotVal any
+
+	if err = json.Unmarshal(data, &wantVal); err != nil {
+		t.Errorf("webtest: 反序列化期望值失败: %v", err)
+		return r
+	}
+
+	if err = r.DecodeJSON(&gotVal); err != nil {
+		t.Errorf("webtest: 响应体不是合法的 json: %v，响应体: %s", err, r.BodyString())
+		return r
+	}
+
+	if !reflect.DeepEqual(wantVal, gotVal) {
+		t.Errorf("webtest: 响应体为 %s，期望 %s", r.BodyString(), data)
+	}
+
+	return r
+}
@@ -0,0 +1,145 @@
+package webtest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/uzziahlin/web/session"
+	"github.com/uzziahlin/web/session/cookie"
+)
+
+// SessionCookieName NewSessionManager 使用的 cookie 名称
+const SessionCookieName = "sessid"
+
+var (
+	errKeyNotFound     = errors.New("webtest: session 中找不到 key")
+	errSessionNotFound = errors.New("webtest: 找不到 session")
+)
+
+var _ session.Store = &SessionStore{}
+
+// SessionStore 用于测试的 session.Store
+// 可以预置 session，也可以在请求之后检查 session 中的数据
+type SessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*Session
+}
+
+func NewSessionStore() *SessionStore {
+	return &SessionStore{
+		sessions: map[string]*Session{},
+	}
+}
+
+// NewSessionManager 创建使用 SessionStore 和 cookie 的 session.Manager
+// 请求中可以通过 Request.WithSession 带上 session id
+func NewSessionManager() (*session.Manager, *SessionStore) {
+	store := NewSessionStore()
+	return &session.Manager{
+		Store:      store,
+		Propagator: cookie.NewPropagator(),
+		SessCtxKey: "webtest-session",
+	}, store
+}
+
+// WithSession 以 NewSessionManager 使用的 cookie 带上 session id
+func (r *Request) WithSession(id string) *Request {
+	return r.WithCookie(&http.Cookie{Name: SessionCookieName, Value: id})
+}
+
+// Put 预置一个 session
+func (s *SessionStore) Put(id string, values map[string]any) *Session {
+	sess := newSession(id)
+	for k, v := range values {
+		sess.values[k] = v
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[id] = sess
+
+	return sess
+}
+
+// Session 获取 session，用于检查请求处理之后 session 中的数据
+func (s *SessionStore) Session(id string) (*Session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[id]
+	return sess, ok
+}
+
+func (s *SessionStore) Generate(ctx context.Context, id string) (session.Session, error) {
+	return s.Put(id, nil), nil
+}
+
+func (s *SessionStore) Refresh(ctx context.Context, id string) error {
+	if _, ok := s.Session(id); !ok {
+		return errSessionNotFound
+	}
+	return nil
+}
+
+func (s *SessionStore) Remove(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+	return nil
+}
+
+func (s *SessionStore) Get(ctx context.Context, id string) (session.Session, error) {
+	sess, ok := s.Session(id)
+	if !ok {
+		return nil, errSessionNotFound
+	}
+	return sess, nil
+}
+
+// Session 用于测试的 session.Session
+type Session struct {
+	id string
+
+	mu     sync.Mutex
+	values map[string]any
+}
+
+func newSession(id string) *Session {
+	return &Session{
+		id:     id,
+		values: map[string]any{},
+	}
+}
+
+func (s *Session) Set(ctx context.Context, key string, value any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+	return nil
+}
+
+func (s *Session) Get(ctx context.Context, key string) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	val, ok := s.values[key]
+	if !ok {
+		return nil, errKeyNotFound
+	}
+	return val, nil
+}
+
+func (s *Session) ID() string {
+	return s.id
+}
+
+// Values 返回 session 中所有数据的副本
+func (s *Session) Values() map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := make(map[string]any, len(s.values))
+	for k, v := range s.values {
+		res[k] = v
+	}
+	return res
+}
@@ -0,0 +1,73 @@
+package webtest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/uzziahlin/web"
+)
+
+func TestRequest_Do(t *testing.T) {
+	manager, store := NewSessionManager()
+	store.Put("abc", map[string]any{"name": "tom"})
+
+	server := web.NewHttpServer(":8080")
+	server.Get("/user/:id", func(ctx *web.Context) {
+		sess, err := manager.GetSession(ctx)
+		if err != nil {
+			ctx.RespStatus = http.StatusUnauthorized
+			return
+		}
+		// 兼容直接从 UserValues 读取 session 的代码
+		if ctx.UserValues["webtest-session"] != sess {
+			ctx.RespStatus = http.StatusInternalServerError
+			return
+		}
+		name, _ := sess.Get(ctx.Req.Context(), "name")
+		_ = sess.Set(ctx.Req.Context(), "visited", ctx.PathParams["id"])
+		ctx.Resp.Header().Set("X-Lang", ctx.Req.URL.Query().Get("lang"))
+		_ = ctx.WriteJSONOK(map[string]any{"id": ctx.PathParams["id"], "name": name})
+	})
+
+	NewRequest(http.MethodGet, "/user/1").
+		WithSession("abc").
+		WithQuery("lang", "en").
+		Do(server).
+		AssertStatus(t, http.StatusOK).
+		AssertHeader(t, "X-Lang", "en").
+		AssertJSON(t, map[string]any{"id": "1", "name": "tom"})
+
+	sess, _ := store.Session("abc")
+	if got := sess.Values()["visited"]; got != "1" {
+		t.Errorf("visited 为 %v，期望 1", got)
+	}
+
+	NewRequest(http.MethodGet, "/user/1").
+		Do(server).
+		AssertStatus(t, http.StatusUnauthorized)
+}
+
+func TestRequest_Call(t *testing.T) {
+	handler := func(ctx *web.Context) {
+		var body struct {
+			Name string `json:"name"`
+		}
+		if err := ctx.BindJSON(&body); err != nil {
+			ctx.RespStatus = http.StatusBadRequest
+			return
+		}
+		ctx.RespStatus = http.StatusCreated
+		ctx.RespData = []byte(ctx.PathParams["id"] + ":" + body.Name)
+	}
+
+	resp := NewRequest(http.MethodPost, "/user/1").
+		WithPathParam("id", "1").
+		WithJSON(map[string]string{"name": "tom"}).
+		Call(handler).
+		AssertStatus(t, http.StatusCreated).
+		AssertBody(t, "1:tom")
+
+	if resp.BodyString() != resp.Body.String() {
+		t.Errorf("BodyString 为 %s，期望 %s", resp.BodyString(), resp.Body.String())
+	}
+}
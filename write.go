@@ -0,0 +1,116 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// WriteString 输出纯文本，和其他 Write 方法一样会覆盖已经设置的 Content-Type
+// 需要其他类型时使用 WriteBytes
+func (c *Context) WriteString(status int, s string) error {
+	c.Resp.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	c.RespStatus = status
+	c.RespData = []byte(s)
+	return nil
+}
+
+// WriteBytes 按照指定的 Content-Type 输出 data
+func (c *Context) WriteBytes(status int, contentType string, data []byte) error {
+	c.Resp.Header().Set("Content-Type", contentType)
+	c.RespStatus = status
+	c.RespData = data
+	return nil
+}
+
+// WriteXML 使用注册的 XML 编解码器输出 resp
+func (c *Context) WriteXML(status int, resp any) error {
+	codec, ok := c.codecs().Lookup(MIMEXML)
+	if !ok {
+		return errXMLCodecNotFound
+	}
+
+	var buf bytes.Buffer
+	if err := codec.Encode(&buf, resp); err != nil {
+		return err
+	}
+
+	return c.WriteBytes(status, "application/xml; charset=utf-8", buf.Bytes())
+}
+
+// Redirect 重定向到 url，status 只能是 301、302、303、307 或者 308
+func (c *Context) Redirect(status int, url string) error {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		return fmt.Errorf("web: 重定向的响应码必须是 301、302、303、307 或者 308，实际为 %d", status)
+	}
+
+	c.Resp.Header().Set("Location", url)
+	c.RespStatus = status
+	c.RespData = nil
+	return nil
+}
+
+// NoContent 输出 204，不带响应体
+func (c *Context) NoContent() error {
+	c.RespStatus = http.StatusNoContent
+	c.RespData = nil
+	return nil
+}
+
+// WriteFile 输出文件，支持 Range、If-Modified-Since 等条件请求
+// 文件直接写入响应，不经过 RespData，文件不存在时返回 ErrNotFound
+func (c *Context) WriteFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ErrNotFound.WithError(err)
+		}
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() {
+		return ErrNotFound
+	}
+
+	http.ServeContent(c.Resp, c.Req, info.Name(), info.ModTime(), f)
+	return nil
+}
+
+// Attachment 以附件的形式输出文件，filename 为空时使用文件本身的名称
+func (c *Context) Attachment(path, filename string) error {
+	if filename == "" {
+		filename = filepath.Base(path)
+	}
+
+	c.Resp.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": filename,
+	}))
+
+	return c.WriteFile(path)
+}
+
+// WriteStream 将 r 中的数据直接写入响应，适用于较大的数据，r 需要调用方关闭
+func (c *Context) WriteStream(status int, contentType string, r io.Reader) error {
+	if contentType != "" {
+		c.Resp.Header().Set("Content-Type", contentType)
+	}
+
+	c.RespStatus = status
+	c.Resp.WriteHeader(status)
+
+	_, err := io.Copy(c.Resp, r)
+	return err
+}
@@ -0,0 +1,119 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContext_WriteHelpers(t *testing.T) {
+	testCases := []struct {
+		name        string
+		handle      func(ctx *Context) error
+		wantStatus  int
+		wantBody    string
+		wantHeaders map[string]string
+	}{
+		{
+			name:        "string",
+			handle:      func(ctx *Context) error { return ctx.WriteString(http.StatusOK, "hello") },
+			wantStatus:  http.StatusOK,
+			wantBody:    "hello",
+			wantHeaders: map[string]string{"Content-Type": "text/plain; charset=utf-8"},
+		},
+		{
+			name: "xml",
+			handle: func(ctx *Context) error {
+				return ctx.WriteXML(http.StatusCreated, codecUser{Name: "tom"})
+			},
+			wantStatus:  http.StatusCreated,
+			wantBody:    "<codecUser><name>tom</name></codecUser>",
+			wantHeaders: map[string]string{"Content-Type": "application/xml; charset=utf-8"},
+		},
+		{
+			name:        "redirect",
+			handle:      func(ctx *Context) error { return ctx.Redirect(http.StatusFound, "/login") },
+			wantStatus:  http.StatusFound,
+			wantHeaders: map[string]string{"Location": "/login"},
+		},
+		{
+			name:       "no content",
+			handle:     func(ctx *Context) error { return ctx.NoContent() },
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name: "stream",
+			handle: func(ctx *Context) error {
+				return ctx.WriteStream(http.StatusOK, "text/csv", strings.NewReader("a,b\n"))
+			},
+			wantStatus:  http.StatusOK,
+			wantBody:    "a,b\n",
+			wantHeaders: map[string]string{"Content-Type": "text/csv"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			ctx := NewContext(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			require.NoError(t, tc.handle(ctx))
+			ctx.WriteResponse()
+
+			assert.Equal(t, tc.wantStatus, resp.Code)
+			assert.Contains(t, resp.Body.String(), tc.wantBody)
+			for k, v := range tc.wantHeaders {
+				assert.Equal(t, v, resp.Header().Get(k))
+			}
+		})
+	}
+
+	ctx := NewContext(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	for _, status := range []int{http.StatusOK, http.StatusMultipleChoices, http.StatusNotModified, http.StatusUseProxy, 306} {
+		assert.Error(t, ctx.Redirect(status, "/login"), status)
+	}
+
+	// 和 WriteBytes 一样覆盖已经设置的 Content-Type
+	ctx.Resp.Header().Set("Content-Type", "text/html")
+	require.NoError(t, ctx.WriteString(http.StatusOK, "hello"))
+	assert.Equal(t, "text/plain; charset=utf-8", ctx.Resp.Header().Get("Content-Type"))
+}
+
+func TestContext_Attachment(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.txt")
+	require.NoError(t, os.WriteFile(path, []byte("0123456789"), 0o644))
+
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Range", "bytes=2-4")
+	resp := httptest.NewRecorder()
+	ctx := NewContext(resp, req)
+
+	require.NoError(t, ctx.Attachment(path, ""))
+	ctx.WriteResponse()
+
+	assert.Equal(t, http.StatusPartialContent, resp.Code)
+	assert.Equal(t, "234", resp.Body.String())
+	assert.Equal(t, "attachment; filename=report.txt", resp.Header().Get("Content-Disposition"))
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-Modified-Since", modTime.UTC().Format(http.TimeFormat))
+	resp = httptest.NewRecorder()
+	ctx = NewContext(resp, req)
+
+	require.NoError(t, ctx.WriteFile(path))
+	assert.Equal(t, http.StatusNotModified, resp.Code)
+
+	err := ctx.WriteFile(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.True(t, errors.Is(err, ErrNotFound))
+}